@@ -0,0 +1,28 @@
+package emf
+
+// Metrics recorded by AddApdex.
+const (
+	ApdexSatisfiedMetric  = "ApdexSatisfied"
+	ApdexToleratingMetric = "ApdexTolerating"
+	ApdexFrustratedMetric = "ApdexFrustrated"
+)
+
+// AddApdex classifies a latency against the Apdex threshold T as satisfied
+// (at most T), tolerating (at most 4T) or frustrated, and counts it in the
+// matching Count metric. The counts accumulate as running sums, and all
+// three metrics are emitted, zero if need be, so that the score of the
+// period can be computed as (satisfied + tolerating/2) / total.
+func (m *CloudWatchMetric) AddApdex(latencyMs, thresholdMs float64) {
+	var satisfied, tolerating, frustrated float64
+	switch {
+	case latencyMs <= thresholdMs:
+		satisfied = 1
+	case latencyMs <= 4*thresholdMs:
+		tolerating = 1
+	default:
+		frustrated = 1
+	}
+	m.Add(ApdexSatisfiedMetric, satisfied)
+	m.Add(ApdexToleratingMetric, tolerating)
+	m.Add(ApdexFrustratedMetric, frustrated)
+}
@@ -0,0 +1,71 @@
+package emf
+
+import "os"
+
+// The properties, or dimensions under WithContextDimensions, that
+// WithAccountID and WithRegion add.
+const (
+	AccountIDProperty = "AccountId"
+	RegionProperty    = "Region"
+)
+
+// Environment variables read by WithRegionFromEnv, in order of precedence.
+const (
+	EnvRegion        = "AWS_REGION"
+	EnvDefaultRegion = "AWS_DEFAULT_REGION"
+)
+
+// WithAccountID adds the AWS account ID the document comes from as the
+// AccountId property, for dashboards spanning several accounts. A property
+// of the same name added explicitly takes precedence.
+func WithAccountID(id string) Option {
+	return func(c *Config) {
+		c.AccountID = id
+	}
+}
+
+// WithRegion adds the AWS region the document comes from as the Region
+// property. A property of the same name added explicitly takes precedence.
+func WithRegion(region string) Option {
+	return func(c *Config) {
+		c.Region = region
+	}
+}
+
+// WithRegionFromEnv is WithRegion with the region read from AWS_REGION, or
+// AWS_DEFAULT_REGION if that is not set, when the option is applied. It
+// does nothing if neither is set.
+func WithRegionFromEnv() Option {
+	return func(c *Config) {
+		for _, env := range []string{EnvRegion, EnvDefaultRegion} {
+			if v := os.Getenv(env); v != "" {
+				c.Region = v
+				return
+			}
+		}
+	}
+}
+
+// WithContextDimensions adds the account ID and region given by
+// WithAccountID and WithRegion to the default dimension set instead of as
+// properties, so that metrics can be told apart by account and region.
+// This multiplies the number of metrics in accounts receiving data from
+// several sources.
+func WithContextDimensions() Option {
+	return func(c *Config) {
+		c.ContextDimensions = true
+	}
+}
+
+// contextMembers returns the account ID and region members configured for
+// the document, keyed by their names.
+func (c *Config) contextMembers() map[string]string {
+	members := make(map[string]string, 2)
+	if c.AccountID != "" {
+		members[AccountIDProperty] = c.AccountID
+	}
+	if c.Region != "" {
+		members[RegionProperty] = c.Region
+	}
+	return members
+}
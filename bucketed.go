@@ -0,0 +1,96 @@
+package emf
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BucketedAggregator is like Collector but also groups events by time
+// bucket, emitting one document per dimension set and bucket with the
+// bucket's start as its timestamp. It suits backfill and batch pipelines
+// where events arrive long after they happened. It is safe for concurrent
+// use.
+type BucketedAggregator struct {
+	namespace string
+	bucket    time.Duration
+
+	mu     sync.Mutex
+	groups map[bucketKey]*CloudWatchMetric
+}
+
+// bucketKey identifies the document an event is aggregated into.
+type bucketKey struct {
+	start      int64 // Unix nanoseconds
+	dimensions string
+}
+
+// NewBucketedAggregator returns an empty BucketedAggregator grouping events
+// into buckets of the given duration, aligned to the Unix epoch. It panics
+// if bucket is not positive.
+func NewBucketedAggregator(namespace string, bucket time.Duration) *BucketedAggregator {
+	if bucket <= 0 {
+		panic("emf: NewBucketedAggregator: bucket duration must be positive")
+	}
+	return &BucketedAggregator{
+		namespace: namespace,
+		bucket:    bucket,
+		groups:    make(map[bucketKey]*CloudWatchMetric),
+	}
+}
+
+// Record adds the event to the document for its dimension set and the
+// bucket containing its timestamp.
+func (a *BucketedAggregator) Record(e Event) {
+	ts := e.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	start := ts.Truncate(a.bucket)
+	key := bucketKey{start: start.UnixNano(), dimensions: dimensionSignature(e.Dimensions)}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	m, ok := a.groups[key]
+	if !ok {
+		m = newEventDocument(a.namespace, e.Dimensions)
+		m.SetTimestamp(start)
+		a.groups[key] = m
+	}
+	m.addEvent(e)
+}
+
+// Len returns the number of documents the next Flush would emit.
+func (a *BucketedAggregator) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.groups)
+}
+
+// Flush emits one document per dimension set and bucket to s, oldest
+// bucket first and then by dimension signature, and resets the
+// aggregator. It stops at the first error; the documents that were not
+// emitted are discarded.
+func (a *BucketedAggregator) Flush(s Sink) error {
+	a.mu.Lock()
+	groups := a.groups
+	a.groups = make(map[bucketKey]*CloudWatchMetric)
+	a.mu.Unlock()
+
+	keys := make([]bucketKey, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].start != keys[j].start {
+			return keys[i].start < keys[j].start
+		}
+		return keys[i].dimensions < keys[j].dimensions
+	})
+	for _, k := range keys {
+		if err := groups[k].EmitTo(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
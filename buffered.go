@@ -0,0 +1,187 @@
+package emf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when emitting to a closed emitter.
+var ErrClosed = errors.New("emf: emitter closed")
+
+// DroppedError is returned by BufferedEmitter.CloseContext when the context
+// ends before every buffered document was written.
+type DroppedError struct {
+	// Dropped is the number of buffered documents that were discarded.
+	Dropped int
+	// Err is the context error.
+	Err error
+}
+
+func (e *DroppedError) Error() string {
+	return fmt.Sprintf("emf: %d buffered documents dropped: %v", e.Dropped, e.Err)
+}
+
+func (e *DroppedError) Unwrap() error {
+	return e.Err
+}
+
+// BufferedEmitter writes documents to its Sink from a background
+// goroutine, so that a slow sink does not block callers until the buffer
+// is full. Emit resolves and decorates documents on the caller's goroutine
+// and queues them in a compact binary form, smaller than their JSON, which
+// the background goroutine converts to EMF JSON when writing them. Emit
+// fails wherever MarshalJSON would, so that marshalling errors are
+// returned by Emit rather than surfacing at write time.
+type BufferedEmitter struct {
+	emitter *Emitter
+	queue   chan []byte
+
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+
+	errMu sync.Mutex
+	err   error
+}
+
+// NewBufferedEmitter returns a BufferedEmitter holding up to size documents
+// before Emit blocks. Close must be called to flush and release the
+// background goroutine.
+func NewBufferedEmitter(sink Sink, size int, opts ...EmitterOption) *BufferedEmitter {
+	b := &BufferedEmitter{
+		emitter: NewEmitter(sink, opts...),
+		queue:   make(chan []byte, size),
+		closing: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Emit marshals m and queues it for writing. It blocks while the buffer is
+// full and returns ErrClosed once the emitter is closing.
+func (b *BufferedEmitter) Emit(m *CloudWatchMetric) error {
+	return b.emit(context.Background(), m)
+}
+
+// EmitContext is like Emit but adds the properties that the extractors
+// given with WithContextProperties find in ctx.
+func (b *BufferedEmitter) EmitContext(ctx context.Context, m *CloudWatchMetric) error {
+	return b.emit(ctx, m)
+}
+
+// emit implements Emit and EmitContext.
+func (b *BufferedEmitter) emit(ctx context.Context, m *CloudWatchMetric) error {
+	docs, err := b.emitter.encode(ctx, m, (*CloudWatchMetric).pack)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := b.enqueue(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue adds a packed document to the buffer.
+func (b *BufferedEmitter) enqueue(doc []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+	select {
+	case b.queue <- doc:
+		return nil
+	case <-b.closing:
+		return ErrClosed
+	}
+}
+
+// Close flushes buffered documents and stops the background goroutine. It
+// returns the first error reported by the sink.
+func (b *BufferedEmitter) Close() error {
+	return b.CloseContext(context.Background())
+}
+
+// CloseContext is like Close but gives up when ctx is done, discarding the
+// documents still buffered and returning a *DroppedError. A write already
+// in progress cannot be interrupted; the background goroutine exits as soon
+// as it returns.
+func (b *BufferedEmitter) CloseContext(ctx context.Context) error {
+	b.closeOnce.Do(func() {
+		close(b.closing)
+		b.mu.Lock()
+		b.closed = true
+		close(b.queue)
+		b.mu.Unlock()
+	})
+
+	select {
+	case <-b.done:
+		return b.sinkErr()
+	case <-ctx.Done():
+	}
+	b.stopOnce.Do(func() { close(b.stop) })
+	dropped := 0
+	for range b.queue {
+		dropped++
+	}
+	atomic.AddUint64(&b.emitter.stats.dropped, uint64(dropped))
+	return &DroppedError{Dropped: dropped, Err: ctx.Err()}
+}
+
+// run writes queued documents until the queue is closed and drained or the
+// emitter is told to stop.
+func (b *BufferedEmitter) run() {
+	defer close(b.done)
+	for {
+		select {
+		case <-b.stop:
+			return
+		case doc, ok := <-b.queue:
+			if !ok {
+				if err := b.emitter.Flush(); err != nil {
+					b.setSinkErr(err)
+				}
+				return
+			}
+			line, err := unpack(doc)
+			if err != nil {
+				b.emitter.failed(err)
+				continue
+			}
+			if err := b.emitter.write(line); err != nil {
+				b.setSinkErr(err)
+			}
+		}
+	}
+}
+
+func (b *BufferedEmitter) setSinkErr(err error) {
+	b.errMu.Lock()
+	if b.err == nil {
+		b.err = err
+	}
+	b.errMu.Unlock()
+}
+
+func (b *BufferedEmitter) sinkErr() error {
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	return b.err
+}
+
+// Stats returns counters describing the emitter's activity so far.
+func (b *BufferedEmitter) Stats() Stats {
+	return b.emitter.Stats()
+}
@@ -0,0 +1,179 @@
+package emf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bufferSink collects the documents it is given.
+type bufferSink struct {
+	mu   sync.Mutex
+	docs [][]byte
+}
+
+func (s *bufferSink) Emit(doc []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs = append(s.docs, append([]byte(nil), doc...))
+	return nil
+}
+
+// blockingSink accepts documents once release is closed.
+type blockingSink chan struct{}
+
+func (s blockingSink) Emit([]byte) error {
+	<-s
+	return nil
+}
+
+// richDocument returns a document using most kinds of root members.
+func richDocument(opts ...Option) CloudWatchMetric {
+	m := NewMetric("NS", append([]Option{WithLevel("INFO"), WithClamp("Ratio", 0, 1), WithClampedCounts(), WithUnitProperties()}, opts...)...)
+	m.SetTimestamp(time.Unix(1700000000, 123456789))
+	m.AddDimension("Service", "api")
+	m.AddDimensionSet(map[string]string{"Service": "api", "Operation": "Get"})
+	m.AddProperty("RequestId", "r-<1>&")
+	m.AddProperty("Attempt", 3)
+	m.AddProperty("Tags", map[string]interface{}{"team": "core", "weight": 0.5, "ids": []int{1, 2}})
+	m.AddProperty("Nil", nil)
+	for _, v := range []float64{1, 2.5, -0.0, 1e300, -7, 1 << 60} {
+		m.AddMetric("Latency", Milliseconds, v)
+	}
+	m.AddMetric("Ratio", None, 3)
+	m.AddHistogram("Sizes", Bytes, []float64{1, 10, 100, 1000}, 3)
+	m.AddStatisticSet("Stat", Count, StatisticSet{Min: 1, Max: 9.5, Sum: 20, SampleCount: 4})
+	m.AddMetricForDimensions(map[string]string{"Operation": "Put"}, "Requests", Count, 1)
+	return m
+}
+
+func TestBufferedEmitterWritesSameDocuments(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"default":          nil,
+		"float numbers":    {WithNumberStyle(NumberFloat)},
+		"values namespace": {WithValuesNamespace("Values"), WithNumberStyle(NumberFloat)},
+		"lexical order":    {WithMemberOrder(MemberOrderLexical)},
+		"array values":     {WithAlwaysArrayValues(), WithEMFVersion("1")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := richDocument(opts...)
+			emitterOpts := []EmitterOption{
+				WithNamespacePrefix("tenant/"),
+				WithDynamicDimension("Host", func() string { return "h1" }),
+			}
+			var plain bytes.Buffer
+			if err := NewEmitter(NewWriterSink(&plain), emitterOpts...).Emit(&m); err != nil {
+				t.Fatal(err)
+			}
+			sink := &bufferSink{}
+			b := NewBufferedEmitter(sink, 10, emitterOpts...)
+			if err := b.Emit(&m); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.Join(sink.docs, nil); !bytes.Equal(got, plain.Bytes()) {
+				t.Errorf("buffered emitter wrote\n%s\nwant\n%s", got, plain.Bytes())
+			}
+		})
+	}
+}
+
+func TestPackedDocumentIsSmaller(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Service", "api")
+	for i := 0; i < 20; i++ {
+		m.AddMetric(strings.Repeat("M", 20)+string(rune('a'+i)), Milliseconds, float64(i))
+	}
+	packed, err := m.pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packed) >= len(doc)/2 {
+		t.Errorf("packed document of %d bytes, want less than half of the %d of its JSON", len(packed), len(doc))
+	}
+}
+
+func TestBufferedEmitterReturnsMarshalErrors(t *testing.T) {
+	b := NewBufferedEmitter(&bufferSink{}, 1)
+	defer b.Close()
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, math.NaN())
+	if err := b.Emit(&m); !errors.Is(err, ErrNonFiniteValue) {
+		t.Errorf("Emit() = %v, want ErrNonFiniteValue", err)
+	}
+	if s := b.Stats(); s.Errors != 1 {
+		t.Errorf("Stats().Errors = %d, want 1", s.Errors)
+	}
+}
+
+func TestBufferedEmitterRejectsNonFiniteProperties(t *testing.T) {
+	for _, v := range []interface{}{math.NaN(), math.Inf(1), map[string]interface{}{"ratio": math.Inf(-1)}, struct{ Ratio float64 }{math.NaN()}} {
+		m := NewMetric("NS")
+		m.AddMetric("Requests", Count, 1)
+		m.AddProperty("Ratio", v)
+		want := NewEmitter(&bufferSink{}).Emit(&m)
+		if want == nil {
+			t.Fatalf("Emitter.Emit() with property %v succeeded", v)
+		}
+		sink := &bufferSink{}
+		b := NewBufferedEmitter(sink, 1)
+		if err := b.Emit(&m); err == nil || err.Error() != want.Error() {
+			t.Errorf("BufferedEmitter.Emit() with property %v = %v, want %v", v, err, want)
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(sink.docs) != 0 {
+			t.Errorf("wrote %d documents, want none", len(sink.docs))
+		}
+	}
+}
+
+func TestUnpackCorrupt(t *testing.T) {
+	m := richDocument()
+	packed, err := m.pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < len(packed); n++ {
+		if _, err := unpack(packed[:n]); err == nil {
+			t.Fatalf("unpack() of the first %d of %d bytes succeeded", n, len(packed))
+		}
+	}
+	if _, err := unpack(append(packed, 0)); err == nil {
+		t.Error("unpack() with trailing bytes succeeded")
+	}
+}
+
+func TestBufferedEmitterCloseContext(t *testing.T) {
+	release := make(chan struct{})
+	b := NewBufferedEmitter(blockingSink(release), 10)
+	m := NewMetric("NS")
+	m.AddMetric("Requests", Count, 1)
+	for i := 0; i < 5; i++ {
+		if err := b.Emit(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var dropped *DroppedError
+	if err := b.CloseContext(ctx); !errors.As(err, &dropped) || dropped.Dropped == 0 {
+		t.Errorf("CloseContext() = %v, want a *DroppedError", err)
+	}
+	if err := b.Emit(&m); err != ErrClosed {
+		t.Errorf("Emit() after close = %v, want ErrClosed", err)
+	}
+	close(release)
+}
@@ -0,0 +1,27 @@
+package emf
+
+// MetricInput is the unit and values of one metric passed to BuildFrom.
+type MetricInput struct {
+	Unit   Unit
+	Values []float64
+}
+
+// BuildFrom returns a document built in one call from maps, for adapters
+// that already hold their data in that form. dims, if not empty, becomes
+// the default dimension set; props are added as properties and metrics
+// are recorded in order of their values. Any of the maps may be nil.
+func BuildFrom(namespace string, dims map[string]string, props map[string]string, metrics map[string]MetricInput, opts ...Option) CloudWatchMetric {
+	m := NewMetric(namespace, opts...)
+	for k, v := range dims {
+		m.AddDimension(k, v)
+	}
+	for _, k := range sortedKeys(props) {
+		m.AddProperty(k, props[k])
+	}
+	for name, in := range metrics {
+		for _, v := range in.Values {
+			m.AddMetric(name, in.Unit, v)
+		}
+	}
+	return m
+}
@@ -0,0 +1,36 @@
+package emf
+
+import (
+	"runtime"
+	"strconv"
+)
+
+// CallerProperty is the property that WithCallerProperty sets.
+const CallerProperty = "Caller"
+
+// callerDepth is the number of stack frames between callerLocation and the
+// caller of the Emit and EmitContext methods of Emitter and
+// BufferedEmitter.
+const callerDepth = 5
+
+// WithCallerProperty makes the emitter record the file:line that called
+// Emit as the Caller property of each document, to trace unexpected metrics
+// back to their origin. skip is the number of additional stack frames to
+// ascend, for callers that wrap Emit in helpers of their own. It walks the
+// stack on every emit, so it is meant for debugging and is off by default.
+func WithCallerProperty(skip int) EmitterOption {
+	return func(e *Emitter) {
+		e.callerProperty = true
+		e.callerSkip = skip
+	}
+}
+
+// callerLocation returns the file:line skip frames above the caller of
+// Emit, or the empty string if the stack is not that deep.
+func callerLocation(skip int) string {
+	_, file, line, ok := runtime.Caller(callerDepth + skip)
+	if !ok {
+		return ""
+	}
+	return file + ":" + strconv.Itoa(line)
+}
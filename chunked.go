@@ -0,0 +1,66 @@
+package emf
+
+import "io"
+
+// FlushChunked writes the document to w, spreading metrics with more than
+// 100 values over as many documents as needed instead of truncating them.
+// The i-th document carries the i-th run of up to 100 values of each
+// metric; metrics with fewer values appear only in the first documents.
+// Statistic sets, metrics reduced by WithReducer and the documents of
+// scoped metrics are written once, with the first document, so that
+// reducers see every value.
+// Every document shares the same dimensions, properties and timestamp. A
+// document within the limit is written as a single line, as by Write.
+func (m *CloudWatchMetric) FlushChunked(w io.Writer) error {
+	chunks := 1
+	for name, mt := range m.metrics {
+		if m.reduces(name) {
+			continue
+		}
+		if n := (len(mt.values) + MaxValuesPerMetric - 1) / MaxValuesPerMetric; n > chunks {
+			chunks = n
+		}
+	}
+	if chunks == 1 {
+		return m.Write(w)
+	}
+
+	ts := m.effectiveTimestamp()
+	for i := 0; i < chunks; i++ {
+		c := m.clone()
+		c.timestamp = ts
+		if i > 0 {
+			c.scoped = nil
+		}
+		for name, mt := range c.metrics {
+			if i > 0 {
+				mt.statSets = nil
+			}
+			lo, hi := i*MaxValuesPerMetric, (i+1)*MaxValuesPerMetric
+			if c.reduces(name) {
+				lo, hi = 0, len(mt.values)
+				if i > 0 {
+					lo = hi
+				}
+			}
+			if lo >= len(mt.values) {
+				if len(mt.statSets) == 0 {
+					delete(c.metrics, name)
+				}
+				mt.values, mt.counts = nil, nil
+				continue
+			}
+			if hi > len(mt.values) {
+				hi = len(mt.values)
+			}
+			mt.values = mt.values[lo:hi]
+			if mt.counts != nil {
+				mt.counts = mt.counts[lo:hi]
+			}
+		}
+		if err := c.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
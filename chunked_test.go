@@ -0,0 +1,140 @@
+package emf
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeLines decodes each line written to buf as a JSON object.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var docs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestFlushChunked(t *testing.T) {
+	m := NewMetric("NS")
+	m.SetTimestamp(time.Unix(1700000000, 0))
+	m.AddDimension("Service", "api")
+	m.AddProperty("RequestId", "r-1")
+	for i := 0; i < 250; i++ {
+		m.AddMetric("Latency", Milliseconds, float64(i))
+	}
+	m.AddMetric("Requests", Count, 1)
+
+	var buf bytes.Buffer
+	if err := m.FlushChunked(&buf); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeLines(t, &buf)
+	if len(docs) != 3 {
+		t.Fatalf("wrote %d documents, want 3", len(docs))
+	}
+	var next float64
+	for i, doc := range docs {
+		values := doc["Latency"].([]interface{})
+		if want := []int{100, 100, 50}[i]; len(values) != want {
+			t.Errorf("document %d has %d values, want %d", i, len(values), want)
+		}
+		for _, v := range values {
+			if v.(float64) != next {
+				t.Fatalf("document %d has value %v, want %v", i, v, next)
+			}
+			next++
+		}
+		if doc["Service"] != "api" || doc["RequestId"] != "r-1" {
+			t.Errorf("document %d lacks the shared dimensions and properties", i)
+		}
+		if ts := doc["_aws"].(map[string]interface{})["Timestamp"]; ts != 1700000000000.0 {
+			t.Errorf("document %d has timestamp %v", i, ts)
+		}
+		if _, ok := doc["Requests"]; ok != (i == 0) {
+			t.Errorf("document %d has Requests = %v, want it in the first document only", i, ok)
+		}
+	}
+}
+
+func TestFlushChunkedWithinLimit(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, 1)
+	var buf bytes.Buffer
+	if err := m.FlushChunked(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(decodeLines(t, &buf)); n != 1 {
+		t.Errorf("wrote %d documents, want 1", n)
+	}
+}
+
+func TestFlushChunkedStatisticSets(t *testing.T) {
+	m := NewMetric("NS")
+	m.SetTimestamp(time.Unix(1700000000, 0))
+	for i := 0; i < 150; i++ {
+		m.AddMetric("Latency", Milliseconds, float64(i))
+	}
+	set := StatisticSet{Min: 1, Max: 9, Sum: 20, SampleCount: 4}
+	m.AddStatisticSet("Stat", Milliseconds, set)
+	m.AddStatisticSet("Mixed", Milliseconds, set)
+	for i := 0; i < 150; i++ {
+		m.AddMetric("Mixed", Milliseconds, 1)
+	}
+
+	var buf bytes.Buffer
+	if err := m.FlushChunked(&buf); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeLines(t, &buf)
+	if len(docs) != 2 {
+		t.Fatalf("wrote %d documents, want 2", len(docs))
+	}
+	if _, ok := docs[0]["Stat"]; !ok {
+		t.Error("first document lacks the statistic set only metric")
+	}
+	if _, ok := docs[1]["Stat"]; ok {
+		t.Error("second document repeats the statistic set only metric")
+	}
+	var count float64
+	for _, doc := range docs {
+		switch v := doc["Mixed"].(type) {
+		case map[string]interface{}:
+			count += v["SampleCount"].(float64)
+		case []interface{}:
+			count += float64(len(v))
+		}
+	}
+	if count != 154 {
+		t.Errorf("Mixed sample count over documents = %v, want 154", count)
+	}
+}
+
+func TestFlushChunkedWritesScopedMetricsOnce(t *testing.T) {
+	m := NewMetric("NS")
+	for i := 0; i < 250; i++ {
+		m.AddMetric("Latency", Milliseconds, float64(i))
+	}
+	m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Requests", Count, 1)
+
+	var buf bytes.Buffer
+	if err := m.FlushChunked(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var scoped int
+	for _, doc := range decodeLines(t, &buf) {
+		if _, ok := doc["Requests"]; ok {
+			scoped++
+		}
+	}
+	if scoped != 1 {
+		t.Errorf("scoped metric written %d times, want 1", scoped)
+	}
+}
@@ -0,0 +1,55 @@
+package emf
+
+// Range is a closed interval of metric values.
+type Range struct {
+	Min, Max float64
+}
+
+// WithClamp clamps the values of the named metric into [min, max] when the
+// document is marshalled, so that a single absurd measurement, such as a
+// latency of a billion milliseconds caused by a bug, cannot skew
+// percentiles. The recorded values are not changed. Statistic sets are
+// not clamped. By default values are emitted as recorded.
+func WithClamp(key string, min, max float64) Option {
+	return func(c *Config) {
+		if c.Clamps == nil {
+			c.Clamps = make(map[string]Range)
+		}
+		c.Clamps[key] = Range{Min: min, Max: max}
+	}
+}
+
+// WithClampedCounts adds a "<name>.clamped" property holding the number of
+// values WithClamp clamped, for metrics where any were.
+func WithClampedCounts() Option {
+	return func(c *Config) {
+		c.CountClamped = true
+	}
+}
+
+// clamp returns values clamped into r, copying them only if needed, and
+// the number of values that were out of range.
+func (r Range) clamp(values []float64) ([]float64, int) {
+	var out []float64
+	n := 0
+	for i, v := range values {
+		c := v
+		if c < r.Min {
+			c = r.Min
+		} else if c > r.Max {
+			c = r.Max
+		}
+		if c == v {
+			continue
+		}
+		if out == nil {
+			out = append([]float64(nil), values...)
+		}
+		out[i] = c
+		n++
+	}
+	if out == nil {
+		return values, 0
+	}
+	return out, n
+}
@@ -0,0 +1,226 @@
+// Package cloudwatchlogs sends EMF documents directly to a CloudWatch Logs
+// log stream with PutLogEvents, for environments without the CloudWatch
+// agent or a Lambda runtime collecting standard output. Its Emitter is an
+// emf.Sink that stamps each document with the time it was emitted and
+// collects them into batches of at most 10,000 events and 1 MiB:
+//
+//	cwl := cloudwatchlogs.NewEmitter(sdklogs.NewFromConfig(cfg), "/app/metrics", "host-1")
+//	defer cwl.Flush(ctx)
+//	err := m.EmitTo(cwl)
+//
+// The log group and stream must already exist. Throttling and transient
+// service errors are retried with jittered exponential backoff, and the
+// sequence token the service expects is picked up from its errors. A batch
+// is sent once it is full or on Flush, so call Flush before the process
+// exits.
+package cloudwatchlogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdklogs "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// PutLogEvents limits. Every event counts eventOverhead bytes towards
+// MaxBatchBytes in addition to its message.
+const (
+	MaxBatchEvents = 10000
+	MaxBatchBytes  = 1 << 20
+	eventOverhead  = 26
+)
+
+// API is the part of the CloudWatch Logs client used by Emitter.
+type API interface {
+	PutLogEvents(ctx context.Context, params *sdklogs.PutLogEventsInput, optFns ...func(*sdklogs.Options)) (*sdklogs.PutLogEventsOutput, error)
+}
+
+// Emitter is an emf.Sink batching documents into PutLogEvents calls. A
+// batch is sent when adding a document would exceed the event or size
+// limit, and on Flush. Throttling, service unavailability and transport
+// errors are retried with exponential backoff and jitter; a rejected
+// sequence token is replaced with the one the service expects and the
+// batch resent. It is safe for concurrent use.
+type Emitter struct {
+	client     API
+	group      string
+	stream     string
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	onFailure  func(events int, err error)
+
+	mu       sync.Mutex
+	batch    []types.InputLogEvent
+	size     int
+	sequence *string
+}
+
+// Option configures an Emitter.
+type Option func(*Emitter)
+
+// WithMaxRetries sets how many times a batch that failed with a retryable
+// error is resent before giving up. The default is 3.
+func WithMaxRetries(n int) Option {
+	return func(e *Emitter) {
+		e.maxRetries = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the cap the delay
+// doubles up to on every further retry. Each delay is randomized between
+// half and all of its value so that throttled emitters do not retry in
+// step. The defaults are 100ms and 5s.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(e *Emitter) {
+		e.backoff, e.maxBackoff = initial, max
+	}
+}
+
+// WithFailureHandler sets a function called with the number of events and
+// the error when a batch is dropped, either because the error is permanent
+// or because the retries are exhausted. The error is also returned to the
+// caller of Emit or Flush.
+func WithFailureHandler(fn func(events int, err error)) Option {
+	return func(e *Emitter) {
+		e.onFailure = fn
+	}
+}
+
+// NewEmitter returns an Emitter putting log events to the named log group
+// and stream, which must exist.
+func NewEmitter(client API, group, stream string, opts ...Option) *Emitter {
+	e := &Emitter{
+		client:     client,
+		group:      group,
+		stream:     stream,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Emit adds doc to the current batch as a log event stamped with the
+// current time, first sending the batch if doc would not fit. It fails if
+// doc exceeds emf.MaxEventBytes.
+func (e *Emitter) Emit(doc []byte) error {
+	if len(doc) > emf.MaxEventBytes {
+		return fmt.Errorf("cloudwatchlogs: document of %d bytes exceeds the event limit of %d", len(doc), emf.MaxEventBytes)
+	}
+	event := types.InputLogEvent{
+		Message:   aws.String(string(doc)),
+		Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var err error
+	if len(e.batch) == MaxBatchEvents || e.size+len(doc)+eventOverhead > MaxBatchBytes {
+		err = e.flush(context.Background())
+	}
+	e.batch = append(e.batch, event)
+	e.size += len(doc) + eventOverhead
+	return err
+}
+
+// Flush sends the current batch.
+func (e *Emitter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.flush(ctx)
+}
+
+// flush sends and clears the batch. The batch is cleared even if it could
+// not be delivered.
+func (e *Emitter) flush(ctx context.Context) error {
+	batch := e.batch
+	e.batch, e.size = nil, 0
+	if len(batch) == 0 {
+		return nil
+	}
+	// PutLogEvents requires events in chronological order.
+	sort.SliceStable(batch, func(i, j int) bool {
+		return aws.ToInt64(batch[i].Timestamp) < aws.ToInt64(batch[j].Timestamp)
+	})
+	err := e.put(ctx, batch)
+	if err != nil && e.onFailure != nil {
+		e.onFailure(len(batch), err)
+	}
+	return err
+}
+
+// put sends batch, retrying as configured.
+func (e *Emitter) put(ctx context.Context, batch []types.InputLogEvent) error {
+	backoff := e.backoff
+	for attempt := 0; ; attempt++ {
+		out, err := e.client.PutLogEvents(ctx, &sdklogs.PutLogEventsInput{
+			LogGroupName:  aws.String(e.group),
+			LogStreamName: aws.String(e.stream),
+			LogEvents:     batch,
+			SequenceToken: e.sequence,
+		})
+		if err == nil {
+			e.sequence = out.NextSequenceToken
+			return nil
+		}
+
+		var invalidToken *types.InvalidSequenceTokenException
+		var accepted *types.DataAlreadyAcceptedException
+		switch {
+		case errors.As(err, &accepted):
+			e.sequence = accepted.ExpectedSequenceToken
+			return nil
+		case errors.As(err, &invalidToken):
+			e.sequence = invalidToken.ExpectedSequenceToken
+		case !retryable(err):
+			return fmt.Errorf("cloudwatchlogs: put log events: %w", err)
+		}
+		if attempt == e.maxRetries {
+			return fmt.Errorf("cloudwatchlogs: %d events not delivered after %d attempts: %w", len(batch), attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cloudwatchlogs: %d events not delivered: %w", len(batch), ctx.Err())
+		case <-time.After(jitter(backoff)):
+		}
+		if backoff *= 2; backoff > e.maxBackoff {
+			backoff = e.maxBackoff
+		}
+	}
+}
+
+// retryable reports whether err is worth retrying: throttling, service
+// errors and errors that did not come from the service at all, such as
+// connection failures.
+func retryable(err error) bool {
+	var throttling *types.ThrottlingException
+	var unavailable *types.ServiceUnavailableException
+	var internal *types.InternalServerException
+	if errors.As(err, &throttling) || errors.As(err, &unavailable) || errors.As(err, &internal) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return !errors.As(err, &apiErr)
+}
+
+// jitter returns a random duration between d/2 and d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
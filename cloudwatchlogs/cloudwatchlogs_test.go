@@ -0,0 +1,100 @@
+package cloudwatchlogs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdklogs "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// fakeClient records the sequence tokens and batch sizes it is sent and
+// returns the queued errors, one per call, before succeeding.
+type fakeClient struct {
+	errs    []error
+	tokens  []string
+	batches []int
+}
+
+func (f *fakeClient) PutLogEvents(ctx context.Context, in *sdklogs.PutLogEventsInput, _ ...func(*sdklogs.Options)) (*sdklogs.PutLogEventsOutput, error) {
+	f.tokens = append(f.tokens, aws.ToString(in.SequenceToken))
+	f.batches = append(f.batches, len(in.LogEvents))
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &sdklogs.PutLogEventsOutput{NextSequenceToken: aws.String("next")}, nil
+}
+
+func TestEmitterRetries(t *testing.T) {
+	client := &fakeClient{errs: []error{
+		&types.ThrottlingException{},
+		&types.InvalidSequenceTokenException{ExpectedSequenceToken: aws.String("expected")},
+	}}
+	e := NewEmitter(client, "group", "stream", WithBackoff(time.Millisecond, time.Millisecond))
+	if err := e.Emit([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.tokens) != 3 || client.tokens[2] != "expected" {
+		t.Errorf("sequence tokens sent = %q, want the expected token on the third call", client.tokens)
+	}
+	if err := e.Emit([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.tokens[len(client.tokens)-1]; got != "next" {
+		t.Errorf("sequence token = %q, want the one returned by the last call", got)
+	}
+}
+
+func TestEmitterGivesUp(t *testing.T) {
+	var dropped int
+	client := &fakeClient{errs: []error{errors.New("reset"), errors.New("reset"), errors.New("reset")}}
+	e := NewEmitter(client, "group", "stream",
+		WithMaxRetries(2),
+		WithBackoff(time.Millisecond, time.Millisecond),
+		WithFailureHandler(func(events int, err error) { dropped = events }))
+	if err := e.Emit([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Flush(context.Background()); err == nil {
+		t.Error("Flush succeeded, want the last error")
+	}
+	if len(client.tokens) != 3 || dropped != 1 {
+		t.Errorf("%d calls and %d events dropped, want 3 and 1", len(client.tokens), dropped)
+	}
+
+	client = &fakeClient{errs: []error{&types.ResourceNotFoundException{}}}
+	e = NewEmitter(client, "group", "stream")
+	if err := e.Emit([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Flush(context.Background()); err == nil || len(client.tokens) != 1 {
+		t.Errorf("Flush = %v after %d calls, want a permanent error after one", err, len(client.tokens))
+	}
+}
+
+func TestEmitterBatches(t *testing.T) {
+	client := &fakeClient{}
+	e := NewEmitter(client, "group", "stream")
+	for i := 0; i < MaxBatchEvents+1; i++ {
+		if err := e.Emit([]byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.batches) != 2 || client.batches[0] != MaxBatchEvents || client.batches[1] != 1 {
+		t.Errorf("batch sizes = %v, want [%d 1]", client.batches, MaxBatchEvents)
+	}
+}
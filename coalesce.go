@@ -0,0 +1,101 @@
+package emf
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RepeatCountProperty is the property that WithCoalesce adds to a document
+// standing in for several identical ones.
+const RepeatCountProperty = "repeatCount"
+
+// WithCoalesce makes the emitter hold each document for up to window and
+// drop consecutive byte-identical copies emitted in that time. The held
+// document is written when the window ends, when a different document is
+// emitted or on Flush; if copies were dropped it carries a repeatCount
+// property with the total number of documents it replaces. This guards
+// against loops that accidentally emit the same document many times.
+// Only identical bytes are coalesced, so documents must share a timestamp
+// and must not be given per-document properties such as a DocumentId.
+// By default documents are written as soon as they are emitted.
+func WithCoalesce(window time.Duration) EmitterOption {
+	return func(e *Emitter) {
+		e.coalescer = &coalescer{window: window}
+	}
+}
+
+// coalescer holds the latest document and counts its repeats.
+type coalescer struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	doc   []byte
+	count int
+	gen   uint64
+	timer *time.Timer
+}
+
+// add records doc, writing the previously held document with send if doc
+// differs from it.
+func (c *coalescer) add(doc []byte, send func([]byte) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.doc != nil && bytes.Equal(c.doc, doc) {
+		c.count++
+		return nil
+	}
+	err := c.flushLocked(send)
+	c.doc, c.count = doc, 1
+	gen := c.gen
+	c.timer = time.AfterFunc(c.window, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.gen == gen {
+			c.flushLocked(send)
+		}
+	})
+	return err
+}
+
+// flush writes the held document, if any.
+func (c *coalescer) flush(send func([]byte) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushLocked(send)
+}
+
+func (c *coalescer) flushLocked(send func([]byte) error) error {
+	if c.doc == nil {
+		return nil
+	}
+	doc, count := c.doc, c.count
+	c.doc, c.count = nil, 0
+	c.gen++
+	c.timer.Stop()
+	if count > 1 {
+		doc = withRepeatCount(doc, count)
+	}
+	return send(doc)
+}
+
+// withRepeatCount returns a copy of the newline-terminated JSON object doc
+// with the repeatCount property added as its last member.
+func withRepeatCount(doc []byte, count int) []byte {
+	end := bytes.LastIndexByte(doc, '}')
+	out := make([]byte, 0, len(doc)+len(RepeatCountProperty)+16)
+	out = append(out, doc[:end]...)
+	out = append(out, `,"`+RepeatCountProperty+`":`...)
+	out = strconv.AppendInt(out, int64(count), 10)
+	return append(out, doc[end:]...)
+}
+
+// Flush writes any document held back by WithCoalesce. It does nothing for
+// emitters without that option.
+func (e *Emitter) Flush() error {
+	if e.coalescer == nil {
+		return nil
+	}
+	return e.coalescer.flush(e.send)
+}
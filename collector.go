@@ -0,0 +1,235 @@
+package emf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a single measurement recorded into a Collector.
+type Event struct {
+	// Dimensions are the dimensions the value is recorded under.
+	Dimensions map[string]string
+	// Properties are added to the document the event is aggregated into.
+	// When events of the same group disagree the last one wins.
+	Properties map[string]interface{}
+	// Timestamp is when the measurement was taken. A Collector emits
+	// each document with the earliest timestamp of its events, and
+	// BucketedAggregator uses it to choose the bucket. The zero value
+	// means the time of flushing for a Collector and the time of
+	// recording for a BucketedAggregator.
+	Timestamp time.Time
+
+	Name  string
+	Unit  Unit
+	Value float64
+}
+
+// EvictedSignaturesMetric is the metric a Collector with a TTL emits the
+// number of groups evicted by a flush as.
+const EvictedSignaturesMetric = "EvictedSignatures"
+
+// Collector aggregates events into one document per distinct dimension
+// set, so that many measurements are emitted as a few documents holding
+// value arrays. It is safe for concurrent use.
+//
+// By default Flush releases every group. With WithTTL groups are kept
+// between flushes and evicted once they go without events for the TTL.
+type Collector struct {
+	namespace string
+	groupKey  GroupKeyFunc
+	ttl       time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*collectorGroup
+}
+
+// collectorGroup is the document of a group and when it last received an
+// event. pending reports whether it has received events since the
+// previous flush.
+type collectorGroup struct {
+	doc      *CloudWatchMetric
+	lastSeen time.Time
+	pending  bool
+}
+
+// GroupKeyFunc returns the key of the group an event with the given
+// dimensions and properties is aggregated into. Property values that are
+// not strings are formatted with fmt.Sprint.
+type GroupKeyFunc func(dims map[string]string, props map[string]string) string
+
+// CollectorOption configures a Collector.
+type CollectorOption func(*Collector)
+
+// WithGroupKey makes the collector aggregate events by the key fn returns
+// instead of by their dimension set, for example to group by a customer ID
+// property. The document of a group carries the dimensions of the first
+// event recorded into it: the dimensions of later events of the group are
+// ignored, so fn should only group events whose dimensions agree.
+func WithGroupKey(fn GroupKeyFunc) CollectorOption {
+	return func(c *Collector) {
+		c.groupKey = fn
+	}
+}
+
+// WithTTL makes the collector keep each group, with its dimensions,
+// properties and metric units, from one flush to the next, emitting it
+// only when it has received events, until it has received none for ttl.
+// Flush then evicts it and emits the number of groups it evicted as an
+// EvictedSignatures count in the collector's namespace, so that
+// cardinality churn is visible. A ttl of zero or less, the default,
+// releases every group on flush and emits no count.
+func WithTTL(ttl time.Duration) CollectorOption {
+	return func(c *Collector) {
+		c.ttl = ttl
+	}
+}
+
+// NewCollector returns an empty Collector for the given namespace.
+func NewCollector(namespace string, opts ...CollectorOption) *Collector {
+	c := &Collector{
+		namespace: namespace,
+		groups:    make(map[string]*collectorGroup),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Record adds the event to the document for its dimension set, or for its
+// group key under WithGroupKey.
+//
+// The document is emitted with the earliest Timestamp among its events, so
+// that a group spanning some time is attributed to when it started rather
+// than to when it was flushed. If no event has a timestamp the document is
+// stamped when it is flushed.
+func (c *Collector) Record(e Event) {
+	key := dimensionSignature(e.Dimensions)
+	if c.groupKey != nil {
+		key = c.groupKey(e.Dimensions, stringProperties(e.Properties))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.groups[key]
+	if !ok {
+		g = &collectorGroup{doc: newEventDocument(c.namespace, e.Dimensions)}
+		c.groups[key] = g
+	}
+	g.lastSeen, g.pending = time.Now(), true
+	m := g.doc
+	if !e.Timestamp.IsZero() && (m.timestamp.IsZero() || e.Timestamp.Before(m.timestamp)) {
+		m.SetTimestamp(e.Timestamp)
+	}
+	m.addEvent(e)
+}
+
+// Len returns the number of groups that received events since the
+// previous flush, whose documents the next Flush emits.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, g := range c.groups {
+		if g.pending {
+			n++
+		}
+	}
+	return n
+}
+
+// Flush emits one document per group that received events since the
+// previous flush to s, ordered by dimension signature or group key, and
+// resets the collector, evicting stale groups under WithTTL. It stops at
+// the first error; the documents that were not emitted are discarded.
+func (c *Collector) Flush(s Sink) error {
+	now := time.Now()
+	c.mu.Lock()
+	docs := make(map[string]*CloudWatchMetric, len(c.groups))
+	evicted := 0
+	for k, g := range c.groups {
+		if c.ttl <= 0 {
+			docs[k] = g.doc
+			delete(c.groups, k)
+			continue
+		}
+		if g.pending {
+			snap := g.doc.Snapshot()
+			docs[k] = &snap
+			g.doc.Reset()
+			g.doc.timestamp = time.Time{}
+			g.pending = false
+		}
+		if now.Sub(g.lastSeen) >= c.ttl {
+			delete(c.groups, k)
+			evicted++
+		}
+	}
+	c.mu.Unlock()
+
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := docs[k].EmitTo(s); err != nil {
+			return err
+		}
+	}
+	if evicted > 0 {
+		m := NewMetric(c.namespace)
+		m.AddMetric(EvictedSignaturesMetric, Count, float64(evicted))
+		return m.EmitTo(s)
+	}
+	return nil
+}
+
+// newEventDocument returns an empty document for events with the given
+// dimensions.
+func newEventDocument(namespace string, dims map[string]string) *CloudWatchMetric {
+	m := NewMetric(namespace)
+	if len(dims) > 0 {
+		m.AddDimensionSet(dims)
+	}
+	return &m
+}
+
+// addEvent adds the value and properties of e to the document.
+func (m *CloudWatchMetric) addEvent(e Event) {
+	m.AddProperties(e.Properties)
+	m.AddMetric(e.Name, e.Unit, e.Value)
+}
+
+// stringProperties returns props with every value formatted as a string.
+func stringProperties(props map[string]interface{}) map[string]string {
+	if props == nil {
+		return nil
+	}
+	s := make(map[string]string, len(props))
+	for k, v := range props {
+		if str, ok := v.(string); ok {
+			s[k] = str
+		} else {
+			s[k] = fmt.Sprint(v)
+		}
+	}
+	return s
+}
+
+// dimensionSignature returns a string identifying the dimension set dims
+// irrespective of map order.
+func dimensionSignature(dims map[string]string) string {
+	keys := sortedKeys(dims)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(dims[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package emf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// decodeDocs decodes the documents collected by sink.
+func decodeDocs(t *testing.T, sink *bufferSink) []map[string]interface{} {
+	t.Helper()
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	docs := make([]map[string]interface{}, len(sink.docs))
+	for i, b := range sink.docs {
+		if err := json.Unmarshal(b, &docs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return docs
+}
+
+func TestCollectorFlush(t *testing.T) {
+	c := NewCollector("NS")
+	for _, host := range []string{"b", "a", "b"} {
+		c.Record(Event{Dimensions: map[string]string{"Host": host}, Name: "Requests", Unit: Count, Value: 1})
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	sink := &bufferSink{}
+	if err := c.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeDocs(t, sink)
+	if len(docs) != 2 || docs[0]["Host"] != "a" || docs[1]["Host"] != "b" {
+		t.Fatalf("flushed %v, want the documents of hosts a and b", docs)
+	}
+	if got, ok := docs[1]["Requests"].([]interface{}); !ok || len(got) != 2 {
+		t.Errorf("host b Requests = %v, want two values", docs[1]["Requests"])
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() after Flush = %d, want 0", c.Len())
+	}
+}
+
+func TestCollectorTTL(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+	c := NewCollector("NS", WithTTL(ttl))
+	record := func(host string) {
+		c.Record(Event{Dimensions: map[string]string{"Host": host}, Name: "Requests", Unit: Count, Value: 1})
+	}
+	record("a")
+	record("b")
+	sink := &bufferSink{}
+	if err := c.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	if docs := decodeDocs(t, sink); len(docs) != 2 {
+		t.Fatalf("first flush wrote %d documents, want 2", len(docs))
+	}
+
+	// b is retained without events: the next flush writes nothing for it.
+	record("a")
+	sink = &bufferSink{}
+	if err := c.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	if docs := decodeDocs(t, sink); len(docs) != 1 || docs[0]["Host"] != "a" {
+		t.Fatalf("second flush wrote %v, want only host a", docs)
+	}
+
+	time.Sleep(ttl + 50*time.Millisecond)
+	record("a")
+	sink = &bufferSink{}
+	if err := c.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeDocs(t, sink)
+	if len(docs) != 2 || docs[0]["Host"] != "a" {
+		t.Fatalf("third flush wrote %v, want host a and the eviction count", docs)
+	}
+	if docs[1][EvictedSignaturesMetric] != 1.0 {
+		t.Errorf("%s = %v, want 1", EvictedSignaturesMetric, docs[1][EvictedSignaturesMetric])
+	}
+	if got := len(c.groups); got != 1 {
+		t.Errorf("collector holds %d groups, want 1", got)
+	}
+}
+
+func TestCollectorGroupKey(t *testing.T) {
+	c := NewCollector("NS", WithGroupKey(func(dims map[string]string, props map[string]string) string {
+		return props["CustomerId"]
+	}))
+	c.Record(Event{Dimensions: map[string]string{"Host": "a"}, Properties: map[string]interface{}{"CustomerId": 42}, Name: "Requests", Unit: Count, Value: 1})
+	c.Record(Event{Dimensions: map[string]string{"Host": "b"}, Properties: map[string]interface{}{"CustomerId": "42"}, Name: "Requests", Unit: Count, Value: 1})
+	c.Record(Event{Dimensions: map[string]string{"Host": "b"}, Properties: map[string]interface{}{"CustomerId": "7"}, Name: "Requests", Unit: Count, Value: 1})
+	sink := &bufferSink{}
+	if err := c.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeDocs(t, sink)
+	if len(docs) != 2 {
+		t.Fatalf("flushed %d documents, want one per customer", len(docs))
+	}
+	if got, ok := docs[0]["Requests"].([]interface{}); !ok || len(got) != 2 {
+		t.Errorf("customer 42 Requests = %v, want the values of both events", docs[0]["Requests"])
+	}
+	if docs[0]["Host"] != "a" {
+		t.Errorf("customer 42 Host = %v, want a, the dimension of its first event", docs[0]["Host"])
+	}
+	if docs[1]["CustomerId"] != "7" || docs[1]["Host"] != "b" {
+		t.Errorf("second document = %v, want customer 7 on host b", docs[1])
+	}
+}
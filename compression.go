@@ -0,0 +1,120 @@
+package emf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Compression selects how sinks that ship documents somewhere other than
+// CloudWatch Logs encode them. Documents written to standard output for
+// CloudWatch must stay plain text and are never compressed.
+type Compression int
+
+const (
+	// NoCompression writes documents as they are.
+	NoCompression Compression = iota
+	// Gzip compresses a batch of documents into a single gzip member in
+	// which each document is framed by its length, a FrameHeaderBytes
+	// big-endian integer. Compressing batches rather than single documents
+	// lets gzip exploit the member names and dimension values documents
+	// repeat. Concatenated members form a valid gzip stream, so a file or
+	// object assembled from batches can be read with ReadDocuments.
+	Gzip
+)
+
+// FrameHeaderBytes is the size of the length prefix of each document in a
+// Gzip batch.
+const FrameHeaderBytes = 4
+
+// String returns the name of the compression.
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// Compress returns the batch of docs encoded with c: under NoCompression
+// the documents one after the other, under Gzip a gzip member holding the
+// framed documents.
+func (c Compression) Compress(docs ...[]byte) ([]byte, error) {
+	switch c {
+	case NoCompression:
+		return bytes.Join(docs, nil), nil
+	case Gzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		var header [FrameHeaderBytes]byte
+		for _, doc := range docs {
+			binary.BigEndian.PutUint32(header[:], uint32(len(doc)))
+			if _, err := zw.Write(header[:]); err != nil {
+				return nil, err
+			}
+			if _, err := zw.Write(doc); err != nil {
+				return nil, err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("emf: unknown compression %v", c)
+}
+
+// ReadDocuments reads a stream of batches encoded with c, such as a file
+// written by a FileSink, and returns the documents they hold. Under
+// NoCompression the newline-terminated documents are split at their
+// newlines.
+func (c Compression) ReadDocuments(r io.Reader) ([][]byte, error) {
+	var docs [][]byte
+	switch c {
+	case NoCompression:
+		br := bufio.NewReader(r)
+		for {
+			doc, err := br.ReadBytes('\n')
+			if len(doc) > 0 {
+				docs = append(docs, doc)
+			}
+			if err == io.EOF {
+				return docs, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	case Gzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		var header [FrameHeaderBytes]byte
+		for {
+			if _, err := io.ReadFull(zr, header[:]); err == io.EOF {
+				return docs, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("emf: read frame: %w", err)
+			}
+			// Copy rather than allocate the announced length, which a
+			// corrupt stream could make arbitrarily large.
+			n := int64(binary.BigEndian.Uint32(header[:]))
+			var doc bytes.Buffer
+			if _, err := io.CopyN(&doc, zr, n); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, fmt.Errorf("emf: read frame: %w", err)
+			}
+			docs = append(docs, doc.Bytes())
+		}
+	}
+	return nil, fmt.Errorf("emf: unknown compression %v", c)
+}
@@ -0,0 +1,127 @@
+package emf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testDocuments returns n marshalled documents differing in one value.
+func testDocuments(t *testing.T, n int) [][]byte {
+	t.Helper()
+	docs := make([][]byte, n)
+	for i := range docs {
+		m := NewMetric("NS")
+		m.SetTimestamp(time.Unix(1700000000, 0))
+		m.AddDimension("Service", "api")
+		m.AddMetric("Latency", Milliseconds, float64(i))
+		var buf bytes.Buffer
+		if err := m.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		docs[i] = buf.Bytes()
+	}
+	return docs
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	docs := testDocuments(t, 3)
+	for _, c := range []Compression{NoCompression, Gzip} {
+		t.Run(c.String(), func(t *testing.T) {
+			// Batches concatenate, as in a file or a Firehose object.
+			var stream []byte
+			for _, batch := range [][][]byte{docs[:2], docs[2:]} {
+				b, err := c.Compress(batch...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stream = append(stream, b...)
+			}
+			got, err := c.ReadDocuments(bytes.NewReader(stream))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(docs) {
+				t.Fatalf("read %d documents, want %d", len(got), len(docs))
+			}
+			for i := range docs {
+				if !bytes.Equal(got[i], docs[i]) {
+					t.Errorf("document %d = %s, want %s", i, got[i], docs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGzipBatchIsSmaller(t *testing.T) {
+	docs := testDocuments(t, 100)
+	batch, err := Gzip.Compress(docs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	separate := 0
+	for _, doc := range docs {
+		b, err := Gzip.Compress(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		separate += len(b)
+	}
+	if len(batch)*4 > separate {
+		t.Errorf("batch of %d bytes, want under a quarter of the %d of separately compressed documents", len(batch), separate)
+	}
+}
+
+func TestGzipReadDocumentsTruncated(t *testing.T) {
+	b, err := Gzip.Compress(testDocuments(t, 1)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A frame announcing 10 bytes followed by 3.
+	var short bytes.Buffer
+	zw := gzip.NewWriter(&short)
+	zw.Write([]byte{0, 0, 0, 10, '{', '}', '\n'})
+	zw.Close()
+	for name, stream := range map[string][]byte{"truncated stream": b[:len(b)/2], "short frame": short.Bytes()} {
+		if _, err := Gzip.ReadDocuments(bytes.NewReader(stream)); err == nil {
+			t.Errorf("ReadDocuments() of a %s succeeded", name)
+		}
+	}
+}
+
+func TestFileSinkGzipRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log.gz")
+	s, err := NewFileSink(path, 0, 0, WithCompression(Gzip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := testDocuments(t, 3)
+	for _, doc := range docs {
+		if err := s.Emit(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Fatalf("file holds %v bytes before Close, want the batch still collected", info.Size())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := Gzip.ReadDocuments(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", docs) {
+		t.Errorf("read %q, want %q", got, docs)
+	}
+}
@@ -0,0 +1,155 @@
+package emf
+
+import "time"
+
+// Config bundles everything that configures a CloudWatchMetric at
+// construction, for services that centralize their telemetry settings.
+// Each Option modifies one field; the zero value of a field selects the
+// default behavior.
+type Config struct {
+	// Namespace is the CloudWatch namespace of the document.
+	Namespace string
+	// Timestamp is the document timestamp; zero means the time of
+	// marshalling. See WithTimestamp.
+	Timestamp time.Time
+	// DefaultDimensions are added to the default dimension set. See
+	// WithDefaultDimensions.
+	DefaultDimensions map[string]string
+	// LimitPolicy controls how CloudWatch limits are enforced. Unlike
+	// NewMetric, NewMetricFromConfig does not consult DefaultLimitPolicy;
+	// the zero value is PolicyTruncate. See WithLimitPolicy.
+	LimitPolicy LimitPolicy
+	// AlwaysArrayValues serializes single values as arrays. See
+	// WithAlwaysArrayValues.
+	AlwaysArrayValues bool
+	// ValuesNamespace nests metric values under a root object. See
+	// WithValuesNamespace.
+	ValuesNamespace string
+	// DisableDimensionMirroring leaves dimension values out of the root.
+	// See WithoutDimensionMirroring.
+	DisableDimensionMirroring bool
+	// DropZeroMetrics leaves out all-zero metrics. See WithDropZeroMetrics.
+	DropZeroMetrics bool
+	// TimeBucketDimension and TimeBucketGranularity derive a dimension from
+	// the timestamp. See WithTimeBucketDimension.
+	TimeBucketDimension   string
+	TimeBucketGranularity time.Duration
+	// StrictTemplates makes unresolved dimension template placeholders an
+	// error. See WithStrictTemplates.
+	StrictTemplates bool
+	// UnitProperties mirrors each metric's unit into a property. See
+	// WithUnitProperties.
+	UnitProperties bool
+	// MaxProperties caps the number of properties emitted, zero meaning
+	// no cap, and OnDroppedProperties is told which were left out. See
+	// WithMaxProperties.
+	MaxProperties       int
+	OnDroppedProperties func(dropped []string)
+	// SkipUndefinedRatios makes AddRatio record nothing for a zero
+	// denominator. See WithSkipUndefinedRatios.
+	SkipUndefinedRatios bool
+	// MaxTimestampAge and MaxTimestampLead bound how far the timestamp
+	// may be behind or ahead of the current time for Validate, zero
+	// meaning the defaults. See WithTimestampWindow.
+	MaxTimestampAge  time.Duration
+	MaxTimestampLead time.Duration
+	// Clamps bounds the values of individual metrics, keyed by metric
+	// name, and CountClamped reports how many were clamped. See WithClamp.
+	Clamps       map[string]Range
+	CountClamped bool
+	// SchemaVersion, if set, is emitted as the _schemaVersion property.
+	// See WithSchemaVersion.
+	SchemaVersion string
+	// EMFVersion overrides the EMF specification version, EMFVersion if
+	// empty. See WithEMFVersion.
+	EMFVersion string
+	// Level, if set, is emitted as the level property. See WithLevel.
+	Level string
+	// Reducers reduce the values of individual metrics to one, keyed by
+	// metric name. See WithReducer.
+	Reducers map[string]Reducer
+	// DimensionValueLimit, if positive, truncates longer dimension values
+	// to that many characters, ending them with DimensionValueSuffix. See
+	// WithDimensionValueTruncation.
+	DimensionValueLimit  int
+	DimensionValueSuffix string
+	// StrictDimensionKeys makes dimension sets with too many keys an
+	// error. See WithStrictDimensionKeys.
+	StrictDimensionKeys bool
+	// NumberStyle is how metric values are written. See WithNumberStyle.
+	NumberStyle NumberStyle
+	// SampleRates are the fractions of values AddMetric stores, keyed by
+	// metric name. See WithValueSampling.
+	SampleRates map[string]float64
+	// ScaleSampled makes AddSampled weight values by the inverse of their
+	// sample rate. See WithSampleScaling.
+	ScaleSampled bool
+	// SortedValues sorts metric values when marshalling. See
+	// WithSortedValues.
+	SortedValues bool
+	// AccountID and Region, if set, identify where the document comes
+	// from. See WithAccountID and WithRegion.
+	AccountID string
+	Region    string
+	// ContextDimensions emits AccountID and Region as dimensions rather
+	// than properties. See WithContextDimensions.
+	ContextDimensions bool
+	// MemberOrder is the order of the root members of the marshalled
+	// document. See WithMemberOrder.
+	MemberOrder MemberOrder
+}
+
+// WithDefaultDimensions adds dims to the default dimension set of the
+// document at construction.
+func WithDefaultDimensions(dims map[string]string) Option {
+	return func(c *Config) {
+		if c.DefaultDimensions == nil {
+			c.DefaultDimensions = make(map[string]string, len(dims))
+		}
+		for k, v := range dims {
+			c.DefaultDimensions[k] = v
+		}
+	}
+}
+
+// NewMetricFromConfig returns an empty document configured by cfg.
+func NewMetricFromConfig(cfg Config) CloudWatchMetric {
+	cfg.DefaultDimensions = copyDimensions(cfg.DefaultDimensions)
+	if cfg.Clamps != nil {
+		clamps := make(map[string]Range, len(cfg.Clamps))
+		for k, r := range cfg.Clamps {
+			clamps[k] = r
+		}
+		cfg.Clamps = clamps
+	}
+	if cfg.Reducers != nil {
+		reducers := make(map[string]Reducer, len(cfg.Reducers))
+		for k, r := range cfg.Reducers {
+			reducers[k] = r
+		}
+		cfg.Reducers = reducers
+	}
+	if cfg.SampleRates != nil {
+		rates := make(map[string]float64, len(cfg.SampleRates))
+		for k, r := range cfg.SampleRates {
+			rates[k] = r
+		}
+		cfg.SampleRates = rates
+	}
+	m := CloudWatchMetric{
+		namespace:  cfg.Namespace,
+		timestamp:  cfg.Timestamp,
+		properties: make(map[string]interface{}),
+		metrics:    make(map[string]*metric),
+		cfg:        cfg,
+	}
+	for k, v := range cfg.DefaultDimensions {
+		m.AddDimension(k, v)
+	}
+	if cfg.ContextDimensions {
+		for k, v := range cfg.contextMembers() {
+			m.AddDimension(k, v)
+		}
+	}
+	return m
+}
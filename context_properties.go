@@ -0,0 +1,20 @@
+package emf
+
+import "context"
+
+// ContextExtractor returns the properties to add to documents emitted with
+// a context, such as the baggage propagated with a request.
+type ContextExtractor func(ctx context.Context) map[string]string
+
+// WithContextProperties makes EmitContext add the entries fn finds in the
+// context as properties of the emitted documents, so that cross-service
+// context such as request baggage is stitched into every document without
+// call sites copying it. Properties the document already has take
+// precedence. Emit uses an empty context. The otelbaggage package
+// provides an extractor for OpenTelemetry baggage; fn must be safe for
+// concurrent use if the emitter is used concurrently.
+func WithContextProperties(fn ContextExtractor) EmitterOption {
+	return func(e *Emitter) {
+		e.contextProperties = append(e.contextProperties, fn)
+	}
+}
@@ -0,0 +1,50 @@
+package emf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestEmitContextAddsContextProperties(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(NewWriterSink(&buf), WithContextProperties(func(ctx context.Context) map[string]string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		if tenant == "" {
+			return nil
+		}
+		return map[string]string{"Tenant": tenant, "RequestId": "from-context"}
+	}))
+	m := NewMetric("NS")
+	m.AddMetric("Requests", Count, 1)
+	m.AddProperty("RequestId", "r-1")
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "t-1")
+	if err := e.EmitContext(ctx, &m); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["Tenant"] != "t-1" {
+		t.Errorf("Tenant = %v, want the context value t-1", doc["Tenant"])
+	}
+	if doc["RequestId"] != "r-1" {
+		t.Errorf("RequestId = %v, want the document's own r-1", doc["RequestId"])
+	}
+	if _, ok := m.properties["Tenant"]; ok {
+		t.Error("EmitContext added the context properties to the metric itself")
+	}
+
+	buf.Reset()
+	if err := e.Emit(&m); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`"Tenant"`)) {
+		t.Errorf("Emit added context properties: %s", buf.Bytes())
+	}
+}
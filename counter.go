@@ -0,0 +1,34 @@
+package emf
+
+// Increment adds 1 to the named Count metric. See Add.
+func (m *CloudWatchMetric) Increment(key string) {
+	m.Add(key, 1)
+}
+
+// Add adds n to the running sum held by the named metric, creating it with
+// the Count unit if needed. Unlike AddMetric, which appends a value per
+// call, the metric keeps a single value however often Add is called, so a
+// counter incremented a thousand times is emitted as one number rather
+// than an array of a thousand ones. CloudWatch then sees one sample per
+// document, so use AddMetric when the distribution of individual values
+// matters. If the metric already holds several values they are first
+// collapsed into their sum.
+func (m *CloudWatchMetric) Add(key string, n float64) {
+	m.lazyInit()
+	mt, ok := m.metrics[key]
+	if !ok {
+		m.metrics[key] = &metric{unit: Count, values: []float64{n}}
+		return
+	}
+	sum := n
+	for i, v := range mt.values {
+		if mt.counts != nil {
+			v *= mt.counts[i]
+		}
+		sum += v
+	}
+	mt.values = append(mt.values[:0], sum)
+	if mt.counts != nil {
+		mt.counts = append(mt.counts[:0], 1)
+	}
+}
@@ -0,0 +1,66 @@
+package emf
+
+import "sort"
+
+// MetricDefinition identifies a CloudWatch metric the document produces,
+// with what is needed to create alarms or dashboards for it.
+type MetricDefinition struct {
+	Namespace string
+	// Name is the metric name as CloudWatch extracts it, including the
+	// WithValuesNamespace prefix.
+	Name string
+	Unit Unit
+	// Dimensions are the dimension names and values of the metric; empty
+	// for the metric aggregated across all dimensions.
+	Dimensions map[string]string
+}
+
+// Definitions returns one MetricDefinition per metric and dimension set of
+// the document, including metrics recorded with AddMetricForDimensions,
+// whatever values were recorded. They are ordered by metric name, then in
+// the order the dimension sets are emitted, with scoped metrics last.
+// Derived dimensions, such as templated ones, take the values they would
+// be emitted with now. Dimension sets and metrics beyond the CloudWatch
+// limits are included; emitter options such as WithNamespacePrefix are
+// not applied.
+func (m *CloudWatchMetric) Definitions() []MetricDefinition {
+	d := m.withDerivedDimensions(m.effectiveTimestamp())
+	sets := d.emittedDimensionSets()
+	if d.aggregateSet || len(sets) == 0 {
+		sets = append(sets, map[string]string{})
+	}
+	defs := d.definitions(d.metrics, sets)
+	for _, doc := range d.scopedDocuments() {
+		defs = append(defs, doc.definitions(doc.metrics, doc.dimensionSets)...)
+	}
+	return defs
+}
+
+// definitions returns the definitions of metrics under each of sets.
+func (m *CloudWatchMetric) definitions(metrics map[string]*metric, sets []map[string]string) []MetricDefinition {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	prefix := ""
+	if m.cfg.ValuesNamespace != "" {
+		prefix = m.cfg.ValuesNamespace + "."
+	}
+	defs := make([]MetricDefinition, 0, len(names)*len(sets))
+	for _, name := range names {
+		for _, set := range sets {
+			dims := make(map[string]string, len(set))
+			for k, v := range set {
+				dims[k] = m.dimensionValue(v)
+			}
+			defs = append(defs, MetricDefinition{
+				Namespace:  m.namespace,
+				Name:       prefix + name,
+				Unit:       metrics[name].unit,
+				Dimensions: dims,
+			})
+		}
+	}
+	return defs
+}
@@ -0,0 +1,40 @@
+package emf
+
+import "unicode/utf8"
+
+// WithDimensionValueTruncation truncates dimension values longer than
+// maxLen characters when the document is marshalled, replacing their end
+// with suffix, for example "...". A maxLen of zero or less uses
+// MaxDimensionValueLength. CloudWatch rejects metrics with longer values,
+// so enabling this, at least with the default, is recommended. By default
+// values are emitted as they are.
+func WithDimensionValueTruncation(maxLen int, suffix string) Option {
+	if maxLen <= 0 {
+		maxLen = MaxDimensionValueLength
+	}
+	return func(c *Config) {
+		c.DimensionValueLimit = maxLen
+		c.DimensionValueSuffix = suffix
+	}
+}
+
+// dimensionValue returns the value a dimension is emitted with.
+func (m *CloudWatchMetric) dimensionValue(v string) string {
+	limit := m.cfg.DimensionValueLimit
+	if limit <= 0 || utf8.RuneCountInString(v) <= limit {
+		return v
+	}
+	suffix := m.cfg.DimensionValueSuffix
+	keep := limit - utf8.RuneCountInString(suffix)
+	if keep < 0 {
+		keep, suffix = limit, ""
+	}
+	n := 0
+	for i := range v {
+		if n == keep {
+			return v[:i] + suffix
+		}
+		n++
+	}
+	return v
+}
@@ -0,0 +1,45 @@
+package emf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// allowedDimensionValues restricts the values of registered dimension keys.
+var allowedDimensionValues = struct {
+	sync.RWMutex
+	m map[string]map[string]bool
+}{m: make(map[string]map[string]bool)}
+
+// RegisterDimensionValues restricts the dimension key to the given values
+// for all documents. Validate reports a document carrying any other value
+// for the key. Registering a key again replaces its allowed values; keys
+// that were never registered are unrestricted.
+func RegisterDimensionValues(key string, allowed ...string) {
+	set := make(map[string]bool, len(allowed))
+	for _, v := range allowed {
+		set[v] = true
+	}
+	allowedDimensionValues.Lock()
+	allowedDimensionValues.m[key] = set
+	allowedDimensionValues.Unlock()
+}
+
+// checkDimensionValues returns an error for the first dimension value that
+// is not allowed for its registered key.
+func (m *CloudWatchMetric) checkDimensionValues() error {
+	allowedDimensionValues.RLock()
+	defer allowedDimensionValues.RUnlock()
+	if len(allowedDimensionValues.m) == 0 {
+		return nil
+	}
+	for i, set := range m.emittedDimensionSets() {
+		for _, k := range sortedKeys(set) {
+			allowed, ok := allowedDimensionValues.m[k]
+			if ok && !allowed[set[k]] {
+				return fieldErrorf(fmt.Sprintf("dimensionSets[%d].%s", i, k), ErrInvalidDimensionValue, "emf: dimension set %d: value %q is not registered for dimension %q", i, set[k], k)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package emf
+
+import (
+	"fmt"
+	"time"
+)
+
+// AddAggregateDimensionSet adds an empty dimension set, which makes
+// CloudWatch additionally extract every metric without dimensions, as an
+// aggregate across all dimension values. Unlike sets that end up empty by
+// accident, it survives PruneEmptyDimensions.
+func (m *CloudWatchMetric) AddAggregateDimensionSet() {
+	m.aggregateSet = true
+}
+
+// PruneEmptyDimensions removes dimension sets that have no keys, such as
+// those left behind by merges or conditional additions. Empty sets are
+// also skipped when the document is marshalled, so pruning only matters to
+// callers inspecting the document. The set added by
+// AddAggregateDimensionSet is kept.
+func (m *CloudWatchMetric) PruneEmptyDimensions() {
+	m.dimensionSets = m.nonEmptyDimensionSets()
+}
+
+// nonEmptyDimensionSets returns the dimension sets that have at least one
+// key, in order.
+func (m *CloudWatchMetric) nonEmptyDimensionSets() []map[string]string {
+	sets := make([]map[string]string, 0, len(m.dimensionSets))
+	for _, set := range m.dimensionSets {
+		if len(set) > 0 {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// dimensionSetCount returns the number of dimension sets that are emitted
+// before truncation.
+func (m *CloudWatchMetric) dimensionSetCount() int {
+	n := len(m.emittedDimensionSets())
+	if m.aggregateSet {
+		n++
+	}
+	return n
+}
+
+// SetPrimaryDimensionSet makes set the first entry of the Dimensions array,
+// which CloudWatch treats as the primary grouping for some aggregations. An
+// equal set added with AddDimensionSet is not emitted twice. Calling it
+// again replaces the previous primary set.
+func (m *CloudWatchMetric) SetPrimaryDimensionSet(set map[string]string) {
+	m.primarySet = copyDimensions(set)
+}
+
+// emittedDimensionSets returns the non-empty dimension sets in the order
+// they are serialized: the primary set first, then every other set that is
+// not equal to it.
+func (m *CloudWatchMetric) emittedDimensionSets() []map[string]string {
+	if len(m.primarySet) == 0 {
+		return m.nonEmptyDimensionSets()
+	}
+	sets := []map[string]string{m.primarySet}
+	for _, set := range m.nonEmptyDimensionSets() {
+		if !equalDimensions(set, m.primarySet) {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// withDerivedDimensions returns m, or a copy of m carrying the dimensions
+// derived at marshal time for the timestamp ts: the time bucket and the
+// templated dimensions.
+func (m *CloudWatchMetric) withDerivedDimensions(ts time.Time) *CloudWatchMetric {
+	if m.cfg.TimeBucketDimension == "" && len(m.templates) == 0 {
+		return m
+	}
+	c := m.clone()
+	if m.cfg.TimeBucketDimension != "" {
+		c.AddDimension(m.cfg.TimeBucketDimension, m.timeBucket(ts))
+	}
+	for key, tmpl := range m.templates {
+		value, _ := renderTemplate(tmpl, m.properties)
+		c.AddDimension(key, value)
+	}
+	return &c
+}
+
+// copyDimensions returns a copy of set.
+func copyDimensions(set map[string]string) map[string]string {
+	c := make(map[string]string, len(set))
+	for k, v := range set {
+		c[k] = v
+	}
+	return c
+}
+
+// equalDimensions reports whether a and b hold the same keys and values.
+func equalDimensions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// WithStrictDimensionKeys makes Validate and MarshalJSON fail when a
+// dimension set has more than MaxDimensionKeys keys, whatever the limit
+// policy, instead of emitting only the first keys in lexical order. Use it
+// when losing a dimension is worse than losing the document. See also
+// AddDimensionStrict and AddDimensionSetStrict.
+func WithStrictDimensionKeys() Option {
+	return func(c *Config) {
+		c.StrictDimensionKeys = true
+	}
+}
+
+// AddDimensionStrict is like AddDimension but returns an error instead of
+// adding the dimension if the default dimension set would then have more
+// than MaxDimensionKeys keys.
+func (m *CloudWatchMetric) AddDimensionStrict(key, value string) error {
+	if len(m.dimensionSets) > 0 {
+		set := m.dimensionSets[0]
+		if _, ok := set[key]; !ok && len(set) >= MaxDimensionKeys {
+			return fieldErrorf("dimensionSets[0]", ErrTooManyDimensions, "emf: adding dimension %q would exceed the limit of %d keys", key, MaxDimensionKeys)
+		}
+	}
+	m.AddDimension(key, value)
+	return nil
+}
+
+// AddDimensionSetStrict is like AddDimensionSet but returns an error instead
+// of adding the set if it has more than MaxDimensionKeys keys.
+func (m *CloudWatchMetric) AddDimensionSetStrict(dims map[string]string) error {
+	if len(dims) > MaxDimensionKeys {
+		return fieldErrorf("dimensionSets", ErrTooManyDimensions, "emf: dimension set of %d keys exceeds the limit of %d", len(dims), MaxDimensionKeys)
+	}
+	m.AddDimensionSet(dims)
+	return nil
+}
+
+// ExpandDimensions adds one dimension set per combination of a set of
+// groupA and a set of groupB, holding the dimensions of both, since
+// CloudWatch only extracts metrics for the exact sets listed. For example,
+// with groupA holding {Service} and {Service, Operation} and groupB holding
+// {Region} and {AvailabilityZone}, four sets are added, pairing each set of
+// groupA with each set of groupB. Nothing is added if either group is
+// empty.
+//
+// The document holds a single root value per dimension key, shared by
+// every set, so the sets cannot disagree on the value of a key: expanding
+// {Service: A} and {Service: B} by regions would emit every combination
+// under the value of the last set. If two of the resulting sets, or a
+// resulting set and a set of the document, give different values to the
+// same key, ExpandDimensions adds nothing and returns an error wrapping
+// ErrDimensionConflict. Record metrics per value with
+// AddMetricForDimensions instead.
+//
+// The product grows quickly, and a document may have at most
+// MaxDimensionSets sets, counting those already added. If the expansion
+// would exceed that, ExpandDimensions adds nothing and returns an error
+// wrapping ErrTooManyDimensions rather than add a subset that the limit
+// policy would then truncate; split the expansion across documents
+// instead.
+func (m *CloudWatchMetric) ExpandDimensions(groupA, groupB []map[string]string) error {
+	n := len(groupA) * len(groupB)
+	if total := m.dimensionSetCount() + n; total > MaxDimensionSets {
+		return fieldErrorf("dimensionSets", ErrTooManyDimensions, "emf: expanding %d by %d dimension sets makes %d sets, exceeding the limit of %d", len(groupA), len(groupB), total, MaxDimensionSets)
+	}
+	expanded := make([]map[string]string, 0, n)
+	for _, a := range groupA {
+		for _, b := range groupB {
+			set := copyDimensions(a)
+			for k, v := range b {
+				set[k] = v
+			}
+			expanded = append(expanded, set)
+		}
+	}
+	sets := append(m.emittedDimensionSets(), expanded...)
+	if i, k, prev := conflictingDimension(sets); i >= 0 {
+		return fieldErrorf("dimensionSets", ErrDimensionConflict, "emf: expanded dimension sets give dimension %q the values %q and %q", k, prev, sets[i][k])
+	}
+	m.dimensionSets = append(m.dimensionSets, expanded...)
+	return nil
+}
+
+// checkDimensionConflicts returns an error if two dimension sets give
+// different values to the same key.
+func (m *CloudWatchMetric) checkDimensionConflicts() error {
+	sets := m.emittedDimensionSets()
+	if i, k, prev := conflictingDimension(sets); i >= 0 {
+		set := sets[i]
+		return fieldErrorf(fmt.Sprintf("dimensionSets[%d].%s", i, k), ErrDimensionConflict, "emf: dimension set %d gives dimension %q the value %q, but an earlier set gives it %q", i, k, set[k], prev)
+	}
+	return nil
+}
+
+// conflictingDimension returns the index of the first of sets giving a key
+// a different value than an earlier set, the key and the earlier value, or
+// -1 if the sets agree.
+func conflictingDimension(sets []map[string]string) (int, string, string) {
+	values := make(map[string]string)
+	for i, set := range sets {
+		for _, k := range sortedKeys(set) {
+			if prev, ok := values[k]; ok && prev != set[k] {
+				return i, k, prev
+			}
+			values[k] = set[k]
+		}
+	}
+	return -1, "", ""
+}
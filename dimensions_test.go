@@ -0,0 +1,104 @@
+package emf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAggregateDimensionSetKeepsOtherSets(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Service", "api")
+	m.AddAggregateDimensionSet()
+	m.AddMetric("Latency", Milliseconds, 5)
+
+	got := m.Resolve().Directives[0].Dimensions
+	want := [][]string{{"Service"}, {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dimensions = %v, want %v", got, want)
+	}
+}
+
+func TestNoDimensionSetsEmitsEmptySet(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, 5)
+
+	got := m.Resolve().Directives[0].Dimensions
+	if !reflect.DeepEqual(got, [][]string{{}}) {
+		t.Errorf("Dimensions = %v, want [[]]", got)
+	}
+}
+
+func TestExpandDimensions(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Service", "api")
+	m.AddMetric("Latency", Milliseconds, 5)
+	a := []map[string]string{{"Service": "api"}, {"Service": "api", "Operation": "Get"}}
+	b := []map[string]string{{"Region": "eu-west-1"}, {"AvailabilityZone": "eu-west-1a"}}
+	if err := m.ExpandDimensions(a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	got := m.Resolve().Directives[0].Dimensions
+	want := [][]string{
+		{"Service"},
+		{"Region", "Service"},
+		{"AvailabilityZone", "Service"},
+		{"Operation", "Region", "Service"},
+		{"AvailabilityZone", "Operation", "Service"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dimensions = %v, want %v", got, want)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestExpandDimensionsRejectsConflictingValues(t *testing.T) {
+	m := NewMetric("NS")
+	a := []map[string]string{{"Service": "A"}, {"Service": "B"}}
+	b := []map[string]string{{"Region": "x"}}
+	if err := m.ExpandDimensions(a, b); !errors.Is(err, ErrDimensionConflict) {
+		t.Errorf("ExpandDimensions() = %v, want ErrDimensionConflict", err)
+	}
+	if len(m.dimensionSets) != 0 {
+		t.Errorf("added %d dimension sets, want none", len(m.dimensionSets))
+	}
+
+	m.AddDimension("Region", "y")
+	if err := m.ExpandDimensions([]map[string]string{{"Service": "A"}}, b); !errors.Is(err, ErrDimensionConflict) {
+		t.Errorf("ExpandDimensions() = %v, want ErrDimensionConflict with the document's sets", err)
+	}
+}
+
+func TestExpandDimensionsLimit(t *testing.T) {
+	m := NewMetric("NS")
+	group := make([]map[string]string, 6)
+	for i := range group {
+		group[i] = map[string]string{fmt.Sprint("Key", i): "v"}
+	}
+	if err := m.ExpandDimensions(group, group); !errors.Is(err, ErrTooManyDimensions) {
+		t.Errorf("ExpandDimensions() = %v, want ErrTooManyDimensions", err)
+	}
+	if len(m.dimensionSets) != 0 {
+		t.Errorf("added %d dimension sets, want none", len(m.dimensionSets))
+	}
+}
+
+func TestValidateReportsConflictingDimensionSets(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, 5)
+	m.AddDimensionSet(map[string]string{"Service": "A"})
+	m.AddDimensionSet(map[string]string{"Service": "B", "Region": "x"})
+
+	err := m.Validate()
+	if !errors.Is(err, ErrDimensionConflict) {
+		t.Fatalf("Validate() = %v, want ErrDimensionConflict", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "dimensionSets[1].Service" {
+		t.Errorf("Field = %q, want dimensionSets[1].Service", fe.Field)
+	}
+}
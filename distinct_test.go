@@ -0,0 +1,48 @@
+package emf
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestDistinctCounterRecordTo(t *testing.T) {
+	d := NewDistinctCounter()
+	for i := 0; i < 1000; i++ {
+		d.Observe(strconv.Itoa(i % 100))
+	}
+	m := NewMetric("NS")
+	d.RecordTo(&m, "Distinct")
+	if got := m.metrics["Distinct"].values; len(got) != 1 || got[0] != 100 {
+		t.Errorf("recorded %v, want [100]", got)
+	}
+	if got := d.Estimate(); got != 0 {
+		t.Errorf("Estimate() after RecordTo = %v, want 0", got)
+	}
+}
+
+func TestDistinctCounterRecordToConcurrent(t *testing.T) {
+	d := NewDistinctCounter()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10000; i++ {
+			d.Observe(strconv.Itoa(i))
+		}
+	}()
+	m := NewMetric("NS")
+	for i := 0; i < 10; i++ {
+		d.RecordTo(&m, "Distinct")
+	}
+	wg.Wait()
+	d.RecordTo(&m, "Distinct")
+	// Every value lands in exactly one of the recorded estimates.
+	var total float64
+	for _, v := range m.metrics["Distinct"].values {
+		total += v
+	}
+	if total < 9000 || total > 11000 {
+		t.Errorf("recorded estimates sum to %v, want about 10000", total)
+	}
+}
@@ -0,0 +1,14 @@
+// Package emf builds and emits CloudWatch Embedded Metric Format (EMF)
+// documents.
+//
+// A CloudWatchMetric collects metric values, dimensions and properties and
+// serializes them as a single JSON log event that CloudWatch Logs extracts
+// into CloudWatch Metrics:
+//
+//	cwm := emf.NewMetric("MyService")
+//	cwm.AddDimension("Operation", "GetItem")
+//	cwm.AddMetric("Latency", emf.Milliseconds, 12.5)
+//	cwm.Write(os.Stdout)
+//
+// See https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+package emf
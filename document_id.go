@@ -0,0 +1,32 @@
+package emf
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// DocumentIDProperty is the property that WithDocumentID sets.
+const DocumentIDProperty = "DocumentId"
+
+// WithDocumentID makes the emitter add a unique DocumentId property to every
+// document it emits, generated by gen at emit time. A nil gen uses random
+// (version 4) UUIDs; pass a deterministic generator in tests.
+func WithDocumentID(gen func() string) EmitterOption {
+	if gen == nil {
+		gen = newUUID
+	}
+	return func(e *Emitter) {
+		e.documentID = gen
+	}
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("emf: reading random bytes: " + err.Error())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,19 @@
+package emf
+
+// dynamicDimension is a dimension whose value is computed at emit time.
+type dynamicDimension struct {
+	key string
+	fn  func() string
+}
+
+// WithDynamicDimension makes the emitter call fn on every Emit and add the
+// result as the key dimension of the default dimension set, for values such
+// as a deployment color that change over the life of the process. It
+// composes with default dimensions given at construction; on a clash the
+// dynamic value wins. fn must be safe for concurrent use if the emitter is
+// used concurrently.
+func WithDynamicDimension(key string, fn func() string) EmitterOption {
+	return func(e *Emitter) {
+		e.dynamicDimensions = append(e.dynamicDimensions, dynamicDimension{key: key, fn: fn})
+	}
+}
@@ -0,0 +1,176 @@
+// Package emftest provides helpers for testing code instrumented with emf.
+package emftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// Document is a decoded EMF document as captured by CaptureSink.
+type Document map[string]interface{}
+
+// Metric returns the values recorded for the named metric, and whether the
+// document declares it in its metric directives. Values written with
+// counts, as by AddHistogram, are returned once each. A statistic set
+// holds no individual values, so Metric returns none for it; use
+// Statistics instead.
+func (d Document) Metric(name string) ([]float64, bool) {
+	if !d.declares(name) {
+		return nil, false
+	}
+	switch v := d[name].(type) {
+	case float64:
+		return []float64{v}, true
+	case []interface{}:
+		return floats(v), true
+	case map[string]interface{}:
+		values, _ := v["Values"].([]interface{})
+		return floats(values), true
+	}
+	return nil, true
+}
+
+// Statistics returns the statistic set summarizing the named metric,
+// whatever form its values are written in, and whether the document
+// declares it. The SampleCount of values written with counts is the sum
+// of their counts.
+func (d Document) Statistics(name string) (emf.StatisticSet, bool) {
+	if !d.declares(name) {
+		return emf.StatisticSet{}, false
+	}
+	var values, counts []float64
+	switch v := d[name].(type) {
+	case float64:
+		values = []float64{v}
+	case []interface{}:
+		values = floats(v)
+	case map[string]interface{}:
+		if _, ok := v["SampleCount"]; ok {
+			var set emf.StatisticSet
+			set.Min, _ = v["Min"].(float64)
+			set.Max, _ = v["Max"].(float64)
+			set.Sum, _ = v["Sum"].(float64)
+			set.SampleCount, _ = v["SampleCount"].(float64)
+			return set, true
+		}
+		vs, _ := v["Values"].([]interface{})
+		cs, _ := v["Counts"].([]interface{})
+		values, counts = floats(vs), floats(cs)
+	}
+	if len(values) == 0 {
+		return emf.StatisticSet{}, true
+	}
+	set := emf.StatisticSet{Min: math.Inf(1), Max: math.Inf(-1)}
+	for i, v := range values {
+		n := 1.0
+		if i < len(counts) {
+			n = counts[i]
+		}
+		set.Min = math.Min(set.Min, v)
+		set.Max = math.Max(set.Max, v)
+		set.Sum += v * n
+		set.SampleCount += n
+	}
+	return set, true
+}
+
+// floats returns the numbers in a decoded JSON array.
+func floats(a []interface{}) []float64 {
+	values := make([]float64, 0, len(a))
+	for _, e := range a {
+		if f, ok := e.(float64); ok {
+			values = append(values, f)
+		}
+	}
+	return values
+}
+
+// declares reports whether any metric directive in d names the metric.
+func (d Document) declares(name string) bool {
+	aws, _ := d["_aws"].(map[string]interface{})
+	directives, _ := aws["CloudWatchMetrics"].([]interface{})
+	for _, dir := range directives {
+		dir, _ := dir.(map[string]interface{})
+		metrics, _ := dir["Metrics"].([]interface{})
+		for _, def := range metrics {
+			def, _ := def.(map[string]interface{})
+			if def["Name"] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CaptureSink is an emf.Sink that records every emitted document for later
+// assertions. The zero value is ready to use and it is safe for concurrent
+// use.
+type CaptureSink struct {
+	mu   sync.Mutex
+	docs []Document
+}
+
+// Emit decodes and records doc. It fails if doc is not a JSON object.
+func (s *CaptureSink) Emit(doc []byte) error {
+	var d Document
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return fmt.Errorf("emftest: invalid document: %w", err)
+	}
+	s.mu.Lock()
+	s.docs = append(s.docs, d)
+	s.mu.Unlock()
+	return nil
+}
+
+// Documents returns the captured documents in emission order.
+func (s *CaptureSink) Documents() []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Document(nil), s.docs...)
+}
+
+// Len returns the number of captured documents.
+func (s *CaptureSink) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.docs)
+}
+
+// Reset discards all captured documents.
+func (s *CaptureSink) Reset() {
+	s.mu.Lock()
+	s.docs = nil
+	s.mu.Unlock()
+}
+
+// Metric returns every value recorded for the named metric across all
+// captured documents, in emission order.
+func (s *CaptureSink) Metric(name string) []float64 {
+	var values []float64
+	for _, d := range s.Documents() {
+		v, _ := d.Metric(name)
+		values = append(values, v...)
+	}
+	return values
+}
+
+// AssertCount fails t unless exactly expected values were recorded for the
+// named metric across all captured documents. Values are counted by
+// sample, so that those written with counts or as statistic sets count
+// once per sample they stand for.
+func (s *CaptureSink) AssertCount(t testing.TB, name string, expected int) {
+	t.Helper()
+	var got float64
+	for _, d := range s.Documents() {
+		set, _ := d.Statistics(name)
+		got += set.SampleCount
+	}
+	if got != float64(expected) {
+		t.Errorf("metric %q: got %g values, want %d", name, got, expected)
+	}
+}
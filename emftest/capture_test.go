@@ -0,0 +1,69 @@
+package emftest
+
+import (
+	"testing"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+func TestCaptureSink(t *testing.T) {
+	var sink CaptureSink
+	m := emf.NewMetric("NS")
+	m.AddMetric("Latency", emf.Milliseconds, 10)
+	m.AddMetric("Latency", emf.Milliseconds, 30)
+	m.AddMetric("Requests", emf.Count, 1)
+	m.AddHistogram("Sizes", emf.Bytes, []float64{1, 1, 1, 5}, 2)
+	m.AddStatisticSet("Stat", emf.Count, emf.StatisticSet{Min: 1, Max: 9, Sum: 20, SampleCount: 4})
+	m.AddProperty("RequestId", "r-1")
+	if err := m.EmitTo(&sink); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.EmitTo(&sink); err != nil {
+		t.Fatal(err)
+	}
+	if sink.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", sink.Len())
+	}
+
+	doc := sink.Documents()[0]
+	if got, ok := doc.Metric("Latency"); !ok || len(got) != 2 || got[0] != 10 || got[1] != 30 {
+		t.Errorf("Metric(Latency) = %v, %v, want [10 30]", got, ok)
+	}
+	if got, ok := doc.Metric("Requests"); !ok || len(got) != 1 || got[0] != 1 {
+		t.Errorf("Metric(Requests) = %v, %v, want [1]", got, ok)
+	}
+	if _, ok := doc.Metric("RequestId"); ok {
+		t.Error("Metric(RequestId) reports a property as a metric")
+	}
+	if got, ok := doc.Statistics("Stat"); !ok || got != (emf.StatisticSet{Min: 1, Max: 9, Sum: 20, SampleCount: 4}) {
+		t.Errorf("Statistics(Stat) = %+v, %v", got, ok)
+	}
+	if got, ok := doc.Statistics("Latency"); !ok || got != (emf.StatisticSet{Min: 10, Max: 30, Sum: 40, SampleCount: 2}) {
+		t.Errorf("Statistics(Latency) = %+v, %v", got, ok)
+	}
+	if got, ok := doc.Statistics("Sizes"); !ok || got.SampleCount != 4 {
+		t.Errorf("Statistics(Sizes) = %+v, %v, want 4 samples", got, ok)
+	}
+
+	if got := sink.Metric("Latency"); len(got) != 4 {
+		t.Errorf("CaptureSink.Metric(Latency) = %v, want the values of both documents", got)
+	}
+	sink.AssertCount(t, "Latency", 4)
+	sink.AssertCount(t, "Sizes", 8)
+	sink.AssertCount(t, "Stat", 8)
+
+	sink.Reset()
+	if sink.Len() != 0 {
+		t.Errorf("Len() after Reset = %d, want 0", sink.Len())
+	}
+}
+
+func TestCaptureSinkRejectsInvalidDocuments(t *testing.T) {
+	var sink CaptureSink
+	if err := sink.Emit([]byte("not json")); err == nil {
+		t.Error("Emit() of invalid JSON succeeded")
+	}
+	if sink.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", sink.Len())
+	}
+}
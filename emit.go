@@ -0,0 +1,14 @@
+package emf
+
+// Emit writes a document holding a single metric value to Stdout. It is the
+// simplest way to emit a metric from a script or cron job:
+//
+//	emf.Emit("Backups", map[string]string{"Job": "nightly"}, "Duration", emf.Seconds, 42)
+func Emit(namespace string, dims map[string]string, name string, unit Unit, value float64) error {
+	m := NewMetric(namespace)
+	for k, v := range dims {
+		m.AddDimension(k, v)
+	}
+	m.AddMetric(name, unit, value)
+	return m.EmitTo(Stdout)
+}
@@ -0,0 +1,204 @@
+package emf
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Emitter marshals documents and hands them to a Sink. It is safe for
+// concurrent use if its Sink is.
+type Emitter struct {
+	sink              Sink
+	documentID        func() string
+	suppressEmpty     bool
+	callerProperty    bool
+	callerSkip        int
+	coalescer         *coalescer
+	dynamicDimensions []dynamicDimension
+	namespacePrefix   string
+	redactors         []Redactor
+	limiter           *rateLimiter
+	contextProperties []ContextExtractor
+	onError           func(error)
+	stats             emitterStats
+}
+
+// EmitterOption configures an Emitter.
+type EmitterOption func(*Emitter)
+
+// NewEmitter returns an Emitter writing to sink.
+func NewEmitter(sink Sink, opts ...EmitterOption) *Emitter {
+	e := &Emitter{sink: sink}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithSuppressEmpty makes the emitter silently skip documents that would
+// be written without metrics, such as those left empty by Reset, by
+// WithDropZeroMetrics or by WithValueSampling. By default every document
+// is emitted.
+func WithSuppressEmpty() EmitterOption {
+	return func(e *Emitter) {
+		e.suppressEmpty = true
+	}
+}
+
+// WithNamespacePrefix makes the emitter prefix the namespace of every
+// document with prefix, for example "tenant123/", so that shared
+// instrumentation can be routed to per-tenant namespaces. A document whose
+// prefixed namespace CloudWatch would reject, because it is too long or
+// starts with the reserved "AWS/", fails to emit.
+func WithNamespacePrefix(prefix string) EmitterOption {
+	return func(e *Emitter) {
+		e.namespacePrefix = prefix
+	}
+}
+
+// Emit marshals m and hands it to the sink.
+func (e *Emitter) Emit(m *CloudWatchMetric) error {
+	return e.emit(context.Background(), m)
+}
+
+// EmitContext is like Emit but adds the properties that the extractors
+// given with WithContextProperties find in ctx.
+func (e *Emitter) EmitContext(ctx context.Context, m *CloudWatchMetric) error {
+	return e.emit(ctx, m)
+}
+
+// emit implements Emit and EmitContext.
+func (e *Emitter) emit(ctx context.Context, m *CloudWatchMetric) error {
+	docs, err := e.encode(ctx, m, (*CloudWatchMetric).marshalLine)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := e.write(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode returns the documents the emitter writes for m, encoded with enc;
+// none if m is suppressed. Failures are recorded in the emitter's stats.
+func (e *Emitter) encode(ctx context.Context, m *CloudWatchMetric, enc func(*CloudWatchMetric) ([]byte, error)) ([][]byte, error) {
+	docs, err := e.marshal(ctx, m, enc)
+	if err != nil {
+		e.failed(err)
+	}
+	return docs, err
+}
+
+// marshal applies the emitter's options to m and encodes the documents it
+// is written as with enc. Emit-time additions are made to copies so that m
+// is left untouched.
+func (e *Emitter) marshal(ctx context.Context, m *CloudWatchMetric, enc func(*CloudWatchMetric) ([]byte, error)) ([][]byte, error) {
+	if e.namespacePrefix != "" {
+		if err := validateNamespace(e.namespacePrefix + m.Namespace()); err != nil {
+			return nil, err
+		}
+	}
+	var st emitState
+	if e.callerProperty {
+		st.caller = callerLocation(e.callerSkip)
+	}
+	if len(e.dynamicDimensions) > 0 {
+		st.dimensions = make(map[string]string, len(e.dynamicDimensions))
+		for _, d := range e.dynamicDimensions {
+			st.dimensions[d.key] = d.fn()
+		}
+	}
+	for _, extract := range e.contextProperties {
+		for k, v := range extract(ctx) {
+			if st.properties == nil {
+				st.properties = make(map[string]string)
+			}
+			st.properties[k] = v
+		}
+	}
+	var lines [][]byte
+	for _, doc := range m.split() {
+		if e.suppressEmpty && len(doc.emittedMetricNames(doc.effectiveTimestamp())) == 0 {
+			continue
+		}
+		if e.decorating() {
+			c := doc.clone()
+			e.decorate(&c, st)
+			doc = &c
+		}
+		b, err := enc(doc)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, b)
+	}
+	return lines, nil
+}
+
+// emitState holds what the emitter evaluates once per Emit call.
+type emitState struct {
+	// caller is the emitting call site, if recorded.
+	caller string
+	// dimensions are the values of the dynamic dimensions.
+	dimensions map[string]string
+	// properties are the properties extracted from the context.
+	properties map[string]string
+}
+
+// decorating reports whether the emitter adds anything to the documents it
+// emits.
+func (e *Emitter) decorating() bool {
+	return e.documentID != nil || e.callerProperty || len(e.dynamicDimensions) > 0 ||
+		e.namespacePrefix != "" || len(e.redactors) > 0 || len(e.contextProperties) > 0
+}
+
+// decorate adds the emit-time members to a copy of a document.
+func (e *Emitter) decorate(c *CloudWatchMetric, st emitState) {
+	if e.documentID != nil {
+		c.AddProperty(DocumentIDProperty, e.documentID())
+	}
+	if st.caller != "" {
+		c.AddProperty(CallerProperty, st.caller)
+	}
+	for k, v := range st.dimensions {
+		c.AddDimension(k, v)
+	}
+	for k, v := range st.properties {
+		if _, ok := c.properties[k]; !ok {
+			c.AddProperty(k, v)
+		}
+	}
+	if e.namespacePrefix != "" {
+		c.SetNamespace(e.namespacePrefix + c.Namespace())
+	}
+	if len(e.redactors) > 0 {
+		c.redact(e.redactors)
+	}
+}
+
+// write hands a marshalled document to the sink, or to the coalescer if
+// the emitter has one.
+func (e *Emitter) write(doc []byte) error {
+	if e.coalescer != nil {
+		return e.coalescer.add(doc, e.send)
+	}
+	return e.send(doc)
+}
+
+// send hands a marshalled document to the sink, subject to the rate limit,
+// recording the outcome in the emitter's stats.
+func (e *Emitter) send(doc []byte) error {
+	if e.limiter != nil && !e.limiter.take() {
+		atomic.AddUint64(&e.stats.rateLimited, 1)
+		return nil
+	}
+	if err := e.sink.Emit(doc); err != nil {
+		e.failed(err)
+		return err
+	}
+	atomic.AddUint64(&e.stats.emitted, 1)
+	atomic.AddUint64(&e.stats.bytes, uint64(len(doc)))
+	return nil
+}
@@ -0,0 +1,42 @@
+package emf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSuppressEmpty(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		fill func(m *CloudWatchMetric)
+		want bool
+	}{
+		{"values", nil, func(m *CloudWatchMetric) { m.AddMetric("Latency", Milliseconds, 5) }, true},
+		{"nothing recorded", nil, func(m *CloudWatchMetric) {}, false},
+		{"reset", nil, func(m *CloudWatchMetric) {
+			m.AddMetric("Latency", Milliseconds, 5)
+			m.Reset()
+		}, false},
+		{"zero values dropped", []Option{WithDropZeroMetrics()}, func(m *CloudWatchMetric) {
+			m.AddMetric("Errors", Count, 0)
+		}, false},
+		{"scoped only", nil, func(m *CloudWatchMetric) {
+			m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Requests", Count, 1)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := NewEmitter(NewWriterSink(&buf), WithSuppressEmpty())
+			m := NewMetric("NS", tt.opts...)
+			tt.fill(&m)
+			if err := e.Emit(&m); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.Len() > 0; got != tt.want {
+				t.Errorf("emitted = %v, want %v: %s", got, tt.want, buf.Bytes())
+			}
+		})
+	}
+}
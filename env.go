@@ -0,0 +1,70 @@
+package emf
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by NewMetricFromEnv, following the official
+// aws-embedded-metrics libraries.
+const (
+	EnvNamespace    = "AWS_EMF_NAMESPACE"
+	EnvServiceName  = "AWS_EMF_SERVICE_NAME"
+	EnvServiceType  = "AWS_EMF_SERVICE_TYPE"
+	EnvLogGroupName = "AWS_EMF_LOG_GROUP_NAME"
+)
+
+// DefaultNamespace is the namespace NewMetricFromEnv falls back to, the
+// same as that of the official aws-embedded-metrics libraries.
+const DefaultNamespace = "aws-embedded-metrics"
+
+// NewMetricFromEnv returns an empty document configured the way the
+// official aws-embedded-metrics libraries configure theirs, to ease
+// migrating from them. The namespace is taken from AWS_EMF_NAMESPACE,
+// falling back to namespace and then to DefaultNamespace. The ServiceName,
+// ServiceType and LogGroup default dimensions are taken from
+// AWS_EMF_SERVICE_NAME, AWS_EMF_SERVICE_TYPE and AWS_EMF_LOG_GROUP_NAME
+// when set, overriding default dimensions of the same name in opts.
+func NewMetricFromEnv(namespace string, opts ...Option) CloudWatchMetric {
+	if ns := os.Getenv(EnvNamespace); ns != "" {
+		namespace = ns
+	}
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	dims := make(map[string]string)
+	for key, env := range map[string]string{
+		"ServiceName": EnvServiceName,
+		"ServiceType": EnvServiceType,
+		"LogGroup":    EnvLogGroupName,
+	} {
+		if v := os.Getenv(env); v != "" {
+			dims[key] = v
+		}
+	}
+	if len(dims) > 0 {
+		opts = append(opts[:len(opts):len(opts)], WithDefaultDimensions(dims))
+	}
+	return NewMetric(namespace, opts...)
+}
+
+// EnvTestTimestamp names an environment variable that, when set to a Unix
+// time in milliseconds, is used as the timestamp of every document that
+// has none of its own, in place of the time of marshalling. It makes
+// output byte-stable in integration tests without injecting a clock.
+// Unparsable values are ignored.
+const EnvTestTimestamp = "AWS_EMF_TEST_TIMESTAMP"
+
+// testTimestamp returns the time given by AWS_EMF_TEST_TIMESTAMP, if set.
+func testTimestamp() (time.Time, bool) {
+	v, ok := os.LookupEnv(EnvTestTimestamp)
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)), true
+}
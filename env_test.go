@@ -0,0 +1,50 @@
+package emf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTestTimestamp(t *testing.T) {
+	t.Setenv(EnvTestTimestamp, "1700000000123")
+	want := time.Unix(0, 1700000000123*int64(time.Millisecond))
+
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, 5)
+	if got := m.Timestamp(); !got.Equal(want) {
+		t.Errorf("Timestamp() = %v, want %v", got, want)
+	}
+	first, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	second, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) || !bytes.Contains(first, []byte(`"Timestamp":1700000000123`)) {
+		t.Errorf("documents %s and %s are not stamped with the test timestamp", first, second)
+	}
+
+	d := NewSharedDocument()
+	d.Add(&m)
+	b, err := d.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"Timestamp":1700000000123`)) {
+		t.Errorf("shared document %s is not stamped with the test timestamp", b)
+	}
+}
+
+func TestTestTimestampDoesNotOverrideSetTimestamp(t *testing.T) {
+	t.Setenv(EnvTestTimestamp, "1700000000123")
+	m := NewMetric("NS")
+	set := time.Unix(1600000000, 0)
+	m.SetTimestamp(set)
+	if got := m.Timestamp(); !got.Equal(set) {
+		t.Errorf("Timestamp() = %v, want %v", got, set)
+	}
+}
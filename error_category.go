@@ -0,0 +1,23 @@
+package emf
+
+// The metric and dimension recorded by RecordErrorCategory.
+const (
+	ErrorsMetric      = "Errors"
+	CategoryDimension = "Category"
+)
+
+// RecordErrorCategory counts an error of the given category, such as
+// "timeout", "validation" or "dependency", as a Count value of the Errors
+// metric recorded with AddMetricForDimensions under the default dimension
+// set plus a Category dimension, so that errors of several categories can
+// be counted in one document without the Category dimension applying to
+// its other metrics. err, if not nil, is added as the Error property and
+// raises the level as RecordOutcome does. Register the known categories
+// with RegisterDimensionValues to keep the dimension's cardinality
+// bounded.
+func (m *CloudWatchMetric) RecordErrorCategory(category string, err error) {
+	m.AddMetricForDimensions(m.defaultDimensionsWith(CategoryDimension, category), ErrorsMetric, Count, 1)
+	if err != nil {
+		m.addError(err)
+	}
+}
@@ -0,0 +1,93 @@
+package emf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of validation failure. Validate, ValidateAll, MarshalJSON and the
+// strict dimension methods return errors that wrap one of them, so that
+// callers can test for a cause with errors.Is.
+var (
+	// ErrTooManyMetrics: the document defines more than MaxMetrics metrics.
+	ErrTooManyMetrics = errors.New("emf: too many metrics")
+	// ErrTooManyValues: a metric holds more than MaxValuesPerMetric values.
+	ErrTooManyValues = errors.New("emf: too many values")
+	// ErrTooManyDimensions: the document has more than MaxDimensionSets
+	// dimension sets, or a set has more than MaxDimensionKeys keys.
+	ErrTooManyDimensions = errors.New("emf: too many dimensions")
+	// ErrInvalidUnit: a metric has a unit CloudWatch does not know.
+	ErrInvalidUnit = errors.New("emf: invalid unit")
+	// ErrNonFiniteValue: a value, count or statistic is NaN or infinite.
+	ErrNonFiniteValue = errors.New("emf: non-finite value")
+	// ErrReservedNamespace: the namespace uses the reserved "AWS/" prefix.
+	ErrReservedNamespace = errors.New("emf: reserved namespace")
+	// ErrEmptyName: the namespace or a metric name is empty.
+	ErrEmptyName = errors.New("emf: empty name")
+	// ErrInvalidNamespace: the namespace exceeds MaxNamespaceLength.
+	ErrInvalidNamespace = errors.New("emf: invalid namespace")
+	// ErrInvalidStatisticSet: a statistic set is inconsistent.
+	ErrInvalidStatisticSet = errors.New("emf: invalid statistic set")
+	// ErrInvalidDimensionValue: a dimension value is not registered with
+	// RegisterDimensionValues or refers to a missing property.
+	ErrInvalidDimensionValue = errors.New("emf: invalid dimension value")
+	// ErrDimensionConflict: dimension sets give different values to the
+	// same key, which a document can only hold one value for.
+	ErrDimensionConflict = errors.New("emf: conflicting dimension values")
+	// ErrNameCollision: a metric has the name of a property or dimension.
+	ErrNameCollision = errors.New("emf: name collision")
+	// ErrTimestampOutOfRange: a timestamp is outside the window CloudWatch
+	// accepts.
+	ErrTimestampOutOfRange = errors.New("emf: timestamp out of range")
+	// ErrDocumentTooLarge: the document exceeds MaxEventBytes.
+	ErrDocumentTooLarge = errors.New("emf: document too large")
+)
+
+// FieldError is a validation failure of one part of a document.
+type FieldError struct {
+	// Field names the offending part of the document, such as
+	// "namespace", "metrics.Latency" or "dimensionSets[2]".
+	Field string
+	// Err is the kind of failure, one of the Err variables of this
+	// package.
+	Err error
+
+	msg string
+}
+
+// fieldErrorf returns a FieldError of the given kind, with a message
+// formatted from format and args.
+func fieldErrorf(field string, kind error, format string, args ...interface{}) *FieldError {
+	return &FieldError{Field: field, Err: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *FieldError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("%v (%s)", e.Err, e.Field)
+	}
+	return e.msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned by ValidateAll with every reason CloudWatch
+// would reject a document. errors.Is and errors.As look through all of
+// them.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
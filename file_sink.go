@@ -0,0 +1,202 @@
+package emf
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSink writes documents to a file, rotating it when it would grow past
+// a size limit: the file is renamed to path.1, earlier backups move up one
+// number and the oldest beyond the backup count is removed. It is meant for
+// local persistence where no log agent runs, such as development hosts and
+// air-gapped environments.
+type FileSink struct {
+	path        string
+	maxBytes    int64
+	backups     int
+	compression Compression
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+	// batch holds the documents not yet compressed and written, and
+	// batchSize their total size.
+	batch     [][]byte
+	batchSize int
+}
+
+// fileBatchBytes is the size of the documents a compressing FileSink
+// collects before writing them as a batch.
+const fileBatchBytes = 64 << 10
+
+// FileSinkOption configures a FileSink.
+type FileSinkOption func(*FileSink)
+
+// WithCompression makes the sink compress documents in batches before
+// writing them. The sink then collects documents until they amount to
+// 64 KiB and writes them as one batch, so documents not yet written are
+// lost if the process exits without Flush or Close. With Gzip the file,
+// and each rotated backup, is a gzip stream of framed documents, which
+// Gzip.ReadDocuments reads, and maxBytes applies to the compressed size.
+// By default documents are written as plain text as they are emitted.
+func WithCompression(c Compression) FileSinkOption {
+	return func(s *FileSink) {
+		s.compression = c
+	}
+}
+
+// NewFileSink opens path for appending, creating it if needed, and returns
+// a FileSink rotating it once it would exceed maxBytes and keeping up to
+// backups rotated files. A maxBytes of zero or less disables rotation. A
+// single document or batch larger than maxBytes is still written, to a
+// file of its own.
+func NewFileSink(path string, maxBytes int64, backups int, opts ...FileSinkOption) (*FileSink, error) {
+	s := &FileSink{path: path, maxBytes: maxBytes, backups: backups}
+	for _, opt := range opts {
+		opt(s)
+	}
+	f, size, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+	s.f, s.size = f, size
+	return s, nil
+}
+
+// Emit appends doc to the file, rotating it first if needed, or under
+// WithCompression adds it to the batch, writing the batch once it is full.
+// If rotation fails, the document or batch is still appended to the file
+// at the sink's path and the rotation error is returned; the next write
+// tries to rotate again.
+func (s *FileSink) Emit(doc []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ErrClosed
+	}
+	if s.compression == NoCompression {
+		return s.write(doc)
+	}
+	s.batch = append(s.batch, append([]byte(nil), doc...))
+	s.batchSize += len(doc)
+	if s.batchSize < fileBatchBytes {
+		return nil
+	}
+	return s.flush()
+}
+
+// Flush writes the documents collected under WithCompression.
+func (s *FileSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ErrClosed
+	}
+	return s.flush()
+}
+
+// Close writes the documents collected under WithCompression and closes
+// the file. Emit returns ErrClosed afterwards.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.flush()
+	if closeErr := s.f.Close(); err == nil {
+		err = closeErr
+	}
+	s.f = nil
+	return err
+}
+
+// flush compresses the batch and writes it. The batch is cleared even if
+// it could not be written.
+func (s *FileSink) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	b, err := s.compression.Compress(s.batch...)
+	s.batch, s.batchSize = nil, 0
+	if err != nil {
+		return err
+	}
+	return s.write(b)
+}
+
+// write appends b to the file, rotating it first if needed.
+func (s *FileSink) write(b []byte) error {
+	var rotateErr error
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(b)) > s.maxBytes {
+		rotateErr = s.rotate()
+	}
+	n, err := s.f.Write(b)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+// openAppend opens path for appending, creating it if needed, and returns
+// the file with its size.
+func openAppend(path string) (*os.File, int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// rotate shifts the backups and switches to a new file at the sink's path.
+// Whichever step fails, the sink keeps writing rather than closing: the
+// path is reopened, which yields the current file if it was not moved,
+// and the current file is only closed once that succeeds.
+func (s *FileSink) rotate() error {
+	err := s.shift()
+	f, size, openErr := openAppend(s.path)
+	if openErr != nil {
+		if err == nil {
+			err = openErr
+		}
+		return err
+	}
+	if closeErr := s.f.Close(); err == nil {
+		err = closeErr
+	}
+	s.f, s.size = f, size
+	return err
+}
+
+// shift removes the oldest backup and moves the others, and then the file
+// at the sink's path, up one number. Without backups the file at the path
+// is removed instead.
+func (s *FileSink) shift() error {
+	if s.backups <= 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.Remove(s.backup(s.backups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := s.backups - 1; i >= 1; i-- {
+		if err := os.Rename(s.backup(i), s.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(s.path, s.backup(1))
+}
+
+// backup returns the path of the i-th most recent backup.
+func (s *FileSink) backup(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
@@ -0,0 +1,103 @@
+package emf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readFile returns the contents of path, or "" if it does not exist.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	s, err := NewFileSink(path, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []string{"a\n", "b\n", "c\n", "d\n", "e\n", "f\n", "g\n", "h\n"} {
+		if err := s.Emit([]byte(doc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{path: "g\nh\n", path + ".1": "e\nf\n", path + ".2": "c\nd\n", path + ".3": ""} {
+		if got := readFile(t, file); got != want {
+			t.Errorf("%s holds %q, want %q", filepath.Base(file), got, want)
+		}
+	}
+	if err := s.Emit([]byte("i\n")); err != ErrClosed {
+		t.Errorf("Emit() after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestFileSinkRotatesWithoutBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	s, err := NewFileSink(path, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, doc := range []string{"a\n", "b\n", "c\n"} {
+		if err := s.Emit([]byte(doc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readFile(t, path); got != "c\n" {
+		t.Errorf("file holds %q, want %q", got, "c\n")
+	}
+	if got := readFile(t, path+".1"); got != "" {
+		t.Errorf("backup written without backups: %q", got)
+	}
+}
+
+func TestFileSinkRecoversFromFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	s, err := NewFileSink(path, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// A non-empty directory in place of the oldest backup cannot be
+	// removed, so rotation fails before the file is moved.
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Emit([]byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Emit([]byte("b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Emit([]byte("c\n")); err == nil || err == ErrClosed {
+		t.Fatalf("Emit() with a failing rotation = %v, want the rotation error", err)
+	}
+	if got := readFile(t, path); got != "a\nb\nc\n" {
+		t.Errorf("file holds %q after the failed rotation, want every document", got)
+	}
+
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Emit([]byte("d\n")); err != nil {
+		t.Fatalf("Emit() once rotation can succeed = %v", err)
+	}
+	if got := readFile(t, path); got != "d\n" {
+		t.Errorf("file holds %q, want %q", got, "d\n")
+	}
+	if got := readFile(t, path+".1"); got != "a\nb\nc\n" {
+		t.Errorf("backup holds %q, want %q", got, "a\nb\nc\n")
+	}
+}
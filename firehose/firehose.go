@@ -0,0 +1,233 @@
+// Package firehose sends EMF documents to an Amazon Kinesis Data Firehose
+// delivery stream, for pipelines that fan log events out to several
+// destinations. Its Emitter is an emf.Sink that packs documents into
+// PutRecordBatch calls of at most 500 records and 4 MiB, one document per
+// record or, with WithCompression, one compressed batch of documents per
+// record, and resends the records Firehose reports as failed:
+//
+//	fh := firehose.NewEmitter(sdkfirehose.NewFromConfig(cfg), "metrics")
+//	defer fh.Flush(ctx)
+//	err := m.EmitTo(fh)
+//
+// Documents are only sent once a batch is full or on Flush, so call Flush
+// before the process exits.
+package firehose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdkfirehose "github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/firehose/types"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// PutRecordBatch limits.
+const (
+	MaxBatchRecords = 500
+	MaxBatchBytes   = 4 << 20
+	MaxRecordBytes  = 1000 << 10
+)
+
+// API is the part of the Firehose client used by Emitter.
+type API interface {
+	PutRecordBatch(ctx context.Context, params *sdkfirehose.PutRecordBatchInput, optFns ...func(*sdkfirehose.Options)) (*sdkfirehose.PutRecordBatchOutput, error)
+}
+
+// Emitter is an emf.Sink batching documents into PutRecordBatch calls. A
+// batch is sent when adding a document would exceed the record or size
+// limit, and on Flush. Records the service reports as failed are retried
+// with exponential backoff. It is safe for concurrent use.
+type Emitter struct {
+	client      API
+	stream      string
+	maxRetries  int
+	backoff     time.Duration
+	compression emf.Compression
+
+	mu    sync.Mutex
+	batch [][]byte
+	size  int
+	// docs holds the documents waiting to be compressed into a record
+	// under WithCompression, and docsSize their framed size.
+	docs     [][]byte
+	docsSize int
+}
+
+// Option configures an Emitter.
+type Option func(*Emitter)
+
+// WithMaxRetries sets how many times records that failed are resent before
+// giving up. The default is 3.
+func WithMaxRetries(n int) Option {
+	return func(e *Emitter) {
+		e.maxRetries = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry; it doubles on every
+// further retry. The default is 100ms.
+func WithBackoff(d time.Duration) Option {
+	return func(e *Emitter) {
+		e.backoff = d
+	}
+}
+
+// WithCompression makes the emitter compress documents in batches, each
+// filling one record: documents are collected until the next would take
+// them past the record size limit, or until Flush, and compressed
+// together. With emf.Gzip every record is a gzip member of framed
+// documents, so the objects Firehose assembles by concatenating records,
+// for example in S3, are gzip streams that emf.Gzip.ReadDocuments reads;
+// do not also enable compression on the delivery stream. By default each
+// document is sent as a plain-text record of its own.
+func WithCompression(c emf.Compression) Option {
+	return func(e *Emitter) {
+		e.compression = c
+	}
+}
+
+// NewEmitter returns an Emitter putting records to the named delivery
+// stream.
+func NewEmitter(client API, stream string, opts ...Option) *Emitter {
+	e := &Emitter{
+		client:     client,
+		stream:     stream,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Emit adds doc to the current batch, first sending the batch if doc would
+// not fit, or under WithCompression to the documents of the next record.
+// It fails if doc alone exceeds the Firehose record size limit.
+func (e *Emitter) Emit(doc []byte) error {
+	limit, size := MaxRecordBytes, len(doc)
+	if e.compression != emf.NoCompression {
+		limit -= compressedRecordMargin
+		size += emf.FrameHeaderBytes
+	}
+	if size > limit {
+		return fmt.Errorf("firehose: document of %d bytes exceeds the record limit of %d", len(doc), MaxRecordBytes)
+	}
+	doc = append([]byte(nil), doc...)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.compression == emf.NoCompression {
+		return e.add(context.Background(), doc)
+	}
+	var err error
+	if e.docsSize+size > limit {
+		err = e.seal(context.Background())
+	}
+	e.docs = append(e.docs, doc)
+	e.docsSize += size
+	return err
+}
+
+// compressedRecordMargin is the room left in a record for the overhead
+// compression adds to documents that do not compress.
+const compressedRecordMargin = 1 << 10
+
+// Flush compresses the documents collected under WithCompression into a
+// record and sends the current batch.
+func (e *Emitter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.seal(ctx)
+	if flushErr := e.flush(ctx); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// seal compresses the collected documents into a record and adds it to
+// the batch. The documents are cleared even if they could not be
+// compressed.
+func (e *Emitter) seal(ctx context.Context) error {
+	if len(e.docs) == 0 {
+		return nil
+	}
+	record, err := e.compression.Compress(e.docs...)
+	n := len(e.docs)
+	e.docs, e.docsSize = nil, 0
+	if err != nil {
+		return err
+	}
+	if len(record) > MaxRecordBytes {
+		return fmt.Errorf("firehose: %d documents compressed to %d bytes exceed the record limit of %d", n, len(record), MaxRecordBytes)
+	}
+	return e.add(ctx, record)
+}
+
+// add appends record to the batch, first sending the batch if record
+// would not fit.
+func (e *Emitter) add(ctx context.Context, record []byte) error {
+	var err error
+	if len(e.batch) == MaxBatchRecords || e.size+len(record) > MaxBatchBytes {
+		err = e.flush(ctx)
+	}
+	e.batch = append(e.batch, record)
+	e.size += len(record)
+	return err
+}
+
+// flush sends and clears the batch. The batch is cleared even if some
+// records could not be delivered.
+func (e *Emitter) flush(ctx context.Context) error {
+	batch := e.batch
+	e.batch, e.size = nil, 0
+	if len(batch) == 0 {
+		return nil
+	}
+
+	backoff := e.backoff
+	for attempt := 0; ; attempt++ {
+		records := make([]types.Record, len(batch))
+		for i, doc := range batch {
+			records[i] = types.Record{Data: doc}
+		}
+		out, err := e.client.PutRecordBatch(ctx, &sdkfirehose.PutRecordBatchInput{
+			DeliveryStreamName: aws.String(e.stream),
+			Records:            records,
+		})
+		if err != nil {
+			return fmt.Errorf("firehose: put record batch: %w", err)
+		}
+		if aws.ToInt32(out.FailedPutCount) == 0 {
+			return nil
+		}
+
+		var failed [][]byte
+		var lastErr error
+		for i, resp := range out.RequestResponses {
+			if resp.ErrorCode != nil && i < len(batch) {
+				failed = append(failed, batch[i])
+				lastErr = errors.New(aws.ToString(resp.ErrorCode) + ": " + aws.ToString(resp.ErrorMessage))
+			}
+		}
+		if len(failed) == 0 {
+			return fmt.Errorf("firehose: %d records reported failed without details", aws.ToInt32(out.FailedPutCount))
+		}
+		if attempt == e.maxRetries {
+			return fmt.Errorf("firehose: %d records not delivered after %d attempts: %v", len(failed), attempt+1, lastErr)
+		}
+		batch = failed
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("firehose: %d records not delivered: %w", len(failed), ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,129 @@
+package firehose
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdkfirehose "github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/firehose/types"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// fakeClient records the batches it is sent and fails the first record of
+// the first batch if failFirst is set.
+type fakeClient struct {
+	batches   [][][]byte
+	failFirst bool
+}
+
+func (f *fakeClient) PutRecordBatch(ctx context.Context, in *sdkfirehose.PutRecordBatchInput, _ ...func(*sdkfirehose.Options)) (*sdkfirehose.PutRecordBatchOutput, error) {
+	var batch [][]byte
+	out := &sdkfirehose.PutRecordBatchOutput{FailedPutCount: aws.Int32(0)}
+	for i, r := range in.Records {
+		batch = append(batch, r.Data)
+		var entry types.PutRecordBatchResponseEntry
+		if f.failFirst && i == 0 {
+			entry.ErrorCode = aws.String("ServiceUnavailableException")
+			out.FailedPutCount = aws.Int32(1)
+		}
+		out.RequestResponses = append(out.RequestResponses, entry)
+	}
+	f.failFirst = false
+	f.batches = append(f.batches, batch)
+	return out, nil
+}
+
+func TestEmitterBatchesAndRetries(t *testing.T) {
+	client := &fakeClient{failFirst: true}
+	e := NewEmitter(client, "stream", WithBackoff(time.Millisecond))
+	for i := 0; i < MaxBatchRecords+1; i++ {
+		if err := e.Emit([]byte("{}\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var sizes []int
+	for _, b := range client.batches {
+		sizes = append(sizes, len(b))
+	}
+	if len(sizes) != 3 || sizes[0] != MaxBatchRecords || sizes[1] != 1 || sizes[2] != 1 {
+		t.Errorf("batch sizes = %v, want [500 1 1]: a full batch, the retried record and the last record", sizes)
+	}
+}
+
+func TestEmitterGzipRoundTrip(t *testing.T) {
+	client := &fakeClient{}
+	e := NewEmitter(client, "stream", WithCompression(emf.Gzip))
+	var want [][]byte
+	for i := 0; i < 3; i++ {
+		m := emf.NewMetric("NS")
+		m.SetTimestamp(time.Unix(1700000000, 0))
+		m.AddMetric("Requests", emf.Count, float64(i))
+		if err := m.EmitTo(e); err != nil {
+			t.Fatal(err)
+		}
+		var doc bytes.Buffer
+		if err := m.Write(&doc); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, doc.Bytes())
+	}
+	if len(client.batches) != 0 {
+		t.Fatal("documents were sent before Flush")
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.batches) != 1 || len(client.batches[0]) != 1 {
+		t.Fatalf("sent %d batches, want one of one record", len(client.batches))
+	}
+	got, err := emf.Gzip.ReadDocuments(bytes.NewReader(client.batches[0][0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("record holds %d documents, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("document %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEmitterGzipFillsRecords(t *testing.T) {
+	client := &fakeClient{}
+	e := NewEmitter(client, "stream", WithCompression(emf.Gzip))
+	doc := append(bytes.Repeat([]byte("x"), 100<<10), '\n')
+	for i := 0; i < 12; i++ {
+		if err := e.Emit(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var docs int
+	for _, batch := range client.batches {
+		for _, record := range batch {
+			if len(record) > MaxRecordBytes {
+				t.Errorf("record of %d bytes exceeds the limit", len(record))
+			}
+			got, err := emf.Gzip.ReadDocuments(bytes.NewReader(record))
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs += len(got)
+		}
+	}
+	if len(client.batches) != 1 || len(client.batches[0]) != 2 || docs != 12 {
+		t.Errorf("sent %d documents in %d batches, want 12 in one batch of two records", docs, len(client.batches))
+	}
+}
@@ -0,0 +1,28 @@
+package emf
+
+import (
+	"context"
+	"time"
+)
+
+// HeartbeatMetric is the metric emitted by Heartbeat.
+const HeartbeatMetric = "Heartbeat"
+
+// Heartbeat emits a document holding a Heartbeat count of 1 to sink every
+// interval until ctx is done. It blocks, so run it on its own goroutine; it
+// returns promptly once ctx is done. Sink errors are ignored, as a missing
+// heartbeat is itself the signal.
+func Heartbeat(ctx context.Context, interval time.Duration, namespace string, sink Sink) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m := NewMetric(namespace)
+			m.AddMetric(HeartbeatMetric, Count, 1)
+			_ = m.EmitTo(sink)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package emf
+
+import "math"
+
+// WeightedValues is the form a metric with value counts is emitted in:
+// Counts[i] is the number of times Values[i] occurred.
+type WeightedValues struct {
+	Values []float64 `json:"Values"`
+	Counts []float64 `json:"Counts"`
+}
+
+// HistogramBuckets configures how AddHistogramBuckets bins samples.
+type HistogramBuckets struct {
+	// Count is the number of exponentially spaced buckets.
+	Count int
+	// Min and Max bound the bucketed range. Zero values use the smallest
+	// positive sample and the largest sample. Samples outside the range
+	// are counted in the first or last bucket.
+	Min, Max float64
+}
+
+// AddHistogram bins samples into the given number of exponentially spaced
+// buckets between the smallest positive and the largest sample, and records
+// each non-empty bucket's geometric midpoint with the number of samples in
+// it.
+// The metric is emitted with Values and Counts arrays, which keeps
+// percentiles available at a fraction of the size of the raw samples.
+// Samples less than or equal to zero are counted under the value 0; NaN and
+// infinite samples are ignored.
+func (m *CloudWatchMetric) AddHistogram(key string, unit Unit, samples []float64, buckets int) {
+	m.AddHistogramBuckets(key, unit, samples, HistogramBuckets{Count: buckets})
+}
+
+// AddHistogramBuckets is like AddHistogram with configurable bucket bounds.
+// Repeated calls for the same key add to the recorded values and counts.
+func (m *CloudWatchMetric) AddHistogramBuckets(key string, unit Unit, samples []float64, b HistogramBuckets) {
+	finite := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		if !math.IsNaN(s) && !math.IsInf(s, 0) {
+			finite = append(finite, s)
+		}
+	}
+	samples = finite
+	if len(samples) == 0 {
+		return
+	}
+	lo, hi := b.Min, b.Max
+	for _, s := range samples {
+		if b.Min <= 0 && s > 0 && (lo <= 0 || s < lo) {
+			lo = s
+		}
+		if b.Max <= 0 && s > hi {
+			hi = s
+		}
+	}
+	n := b.Count
+	if n < 1 {
+		n = 1
+	}
+
+	counts := make([]float64, n)
+	zeros := 0.0
+	ratio := 1.0
+	if hi > lo {
+		ratio = math.Pow(hi/lo, 1/float64(n))
+	}
+	for _, s := range samples {
+		if s <= 0 {
+			zeros++
+			continue
+		}
+		i := 0
+		if ratio > 1 {
+			i = int(math.Floor(math.Log(s/lo) / math.Log(ratio)))
+		}
+		if i < 0 {
+			i = 0
+		}
+		if i >= n {
+			i = n - 1
+		}
+		counts[i]++
+	}
+
+	m.lazyInit()
+	mt, ok := m.metrics[key]
+	if !ok {
+		mt = &metric{unit: unit, kind: KindHistogram}
+		m.metrics[key] = mt
+	}
+	if mt.counts == nil {
+		mt.counts = make([]float64, len(mt.values))
+		for i := range mt.counts {
+			mt.counts[i] = 1
+		}
+	}
+	if zeros > 0 {
+		mt.values = append(mt.values, 0)
+		mt.counts = append(mt.counts, zeros)
+	}
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		mt.values = append(mt.values, lo*math.Pow(ratio, float64(i)+0.5))
+		mt.counts = append(mt.counts, c)
+	}
+}
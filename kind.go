@@ -0,0 +1,53 @@
+package emf
+
+import "fmt"
+
+// Kind is how a metric was recorded, for exporters mapping metrics onto the
+// type conventions of other systems.
+type Kind int
+
+const (
+	// KindCounter metrics are recorded with AddMetric, Add, Increment and
+	// the helpers built on them.
+	KindCounter Kind = iota
+	// KindGauge metrics are set with SetGauge or ReplaceMetric.
+	KindGauge
+	// KindStatisticSet metrics are created with AddStatisticSet.
+	KindStatisticSet
+	// KindHistogram metrics are created with AddHistogram or
+	// AddHistogramBuckets.
+	KindHistogram
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	case KindStatisticSet:
+		return "statistic set"
+	case KindHistogram:
+		return "histogram"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// SetGauge sets the named metric to the single value, discarding anything
+// previously recorded for it, for a quantity sampled at a point in time
+// such as a queue depth.
+func (m *CloudWatchMetric) SetGauge(key string, unit Unit, value float64) {
+	m.ReplaceMetric(key, unit, value)
+}
+
+// MetricKind returns the kind of the named metric and whether it exists. A
+// metric keeps the kind of the call that created it, except that SetGauge
+// and ReplaceMetric make it a gauge.
+func (m *CloudWatchMetric) MetricKind(key string) (Kind, bool) {
+	mt, ok := m.metrics[key]
+	if !ok {
+		return 0, false
+	}
+	return mt.kind, true
+}
@@ -0,0 +1,20 @@
+package emf
+
+import "sync/atomic"
+
+// ColdStartMetric is the metric recorded by AddColdStart.
+const ColdStartMetric = "ColdStart"
+
+// warm is set once the first AddColdStart call in the process has run.
+var warm int32
+
+// AddColdStart records a ColdStart count of 1 the first time it is called
+// in the process and 0 on every later call. Call it at the top of a Lambda
+// handler so that each invocation reports whether it paid for a cold start.
+func (m *CloudWatchMetric) AddColdStart() {
+	value := 0.0
+	if atomic.CompareAndSwapInt32(&warm, 0, 1) {
+		value = 1
+	}
+	m.AddMetric(ColdStartMetric, Count, value)
+}
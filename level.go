@@ -0,0 +1,19 @@
+package emf
+
+// LevelProperty is the property that WithLevel sets, and LevelError the
+// level RecordOutcome raises it to on failure.
+const (
+	LevelProperty = "level"
+	LevelError    = "ERROR"
+)
+
+// WithLevel adds a level property, such as "INFO", to the document so that
+// log pipelines filtering by level route it like other log events.
+// RecordOutcome raises it to LevelError when recording a failure, and a
+// level property added explicitly takes precedence. By default no level
+// is emitted.
+func WithLevel(level string) Option {
+	return func(c *Config) {
+		c.Level = level
+	}
+}
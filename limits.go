@@ -0,0 +1,130 @@
+package emf
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// CloudWatch limits for a single EMF document.
+const (
+	// MaxMetrics is the number of metrics a document may define.
+	MaxMetrics = 150
+	// MaxValuesPerMetric is the number of values a metric may hold.
+	MaxValuesPerMetric = 100
+	// MaxDimensionKeys is the number of keys a dimension set may have.
+	MaxDimensionKeys = 9
+	// MaxDimensionSets is the number of dimension sets a document may
+	// have.
+	MaxDimensionSets = 30
+	// MaxDimensionValueLength is the number of characters a dimension
+	// value may have.
+	MaxDimensionValueLength = 1024
+	// MaxNamespaceLength is the number of characters a namespace may
+	// have.
+	MaxNamespaceLength = 255
+	// MaxEventBytes is the size of the largest log event CloudWatch Logs
+	// accepts.
+	MaxEventBytes = 256 << 10
+)
+
+// LimitPolicy controls what happens when a document exceeds a CloudWatch
+// limit: 150 metrics, 100 values per metric, 30 dimension sets or 9 keys
+// per dimension set.
+type LimitPolicy int
+
+const (
+	// PolicyTruncate silently drops whatever exceeds a limit when the
+	// document is marshalled. Validate does not report limit violations.
+	PolicyTruncate LimitPolicy = iota
+	// PolicyError makes Validate and MarshalJSON fail on the first limit
+	// violation.
+	PolicyError
+	// PolicyWarn truncates like PolicyTruncate but logs each violation
+	// through the standard logger when the document is marshalled.
+	PolicyWarn
+)
+
+// String returns the name of the policy.
+func (p LimitPolicy) String() string {
+	switch p {
+	case PolicyTruncate:
+		return "truncate"
+	case PolicyError:
+		return "error"
+	case PolicyWarn:
+		return "warn"
+	}
+	return fmt.Sprintf("LimitPolicy(%d)", int(p))
+}
+
+// DefaultLimitPolicy is the policy given to documents created by NewMetric
+// without WithLimitPolicy. NewMetricFromConfig uses Config.LimitPolicy.
+var DefaultLimitPolicy = PolicyTruncate
+
+// WithLimitPolicy sets the limit policy of the document.
+func WithLimitPolicy(p LimitPolicy) Option {
+	return func(c *Config) {
+		c.LimitPolicy = p
+	}
+}
+
+// limitViolations returns every CloudWatch limit the document exceeds, in a
+// stable order.
+func (m *CloudWatchMetric) limitViolations() []error {
+	var errs []error
+	if len(m.metrics) > MaxMetrics {
+		errs = append(errs, fieldErrorf("metrics", ErrTooManyMetrics, "emf: %d metrics exceeds the limit of %d", len(m.metrics), MaxMetrics))
+	}
+	names := make([]string, 0, len(m.metrics))
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if m.reduces(name) {
+			continue
+		}
+		if n := len(m.metrics[name].values); n > MaxValuesPerMetric {
+			errs = append(errs, fieldErrorf("metrics."+name, ErrTooManyValues, "emf: metric %q has %d values, exceeding the limit of %d", name, n, MaxValuesPerMetric))
+		}
+	}
+	if n := m.dimensionSetCount(); n > MaxDimensionSets {
+		errs = append(errs, fieldErrorf("dimensionSets", ErrTooManyDimensions, "emf: %d dimension sets exceeds the limit of %d", n, MaxDimensionSets))
+	}
+	return append(errs, m.dimensionKeyViolations()...)
+}
+
+// dimensionKeyViolations returns an error for every dimension set with more
+// than MaxDimensionKeys keys.
+func (m *CloudWatchMetric) dimensionKeyViolations() []error {
+	var errs []error
+	for i, set := range m.emittedDimensionSets() {
+		if len(set) > MaxDimensionKeys {
+			errs = append(errs, fieldErrorf(fmt.Sprintf("dimensionSets[%d]", i), ErrTooManyDimensions, "emf: dimension set %d has %d keys, exceeding the limit of %d", i, len(set), MaxDimensionKeys))
+		}
+	}
+	return errs
+}
+
+// applyLimitPolicy is called before marshalling. It returns an error only
+// under PolicyError or WithStrictDimensionKeys; truncation itself happens
+// while building the document.
+func (m *CloudWatchMetric) applyLimitPolicy() error {
+	if m.cfg.StrictDimensionKeys {
+		if errs := m.dimensionKeyViolations(); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+	switch m.cfg.LimitPolicy {
+	case PolicyError:
+		if errs := m.limitViolations(); len(errs) > 0 {
+			return errs[0]
+		}
+	case PolicyWarn:
+		for _, err := range m.limitViolations() {
+			log.Printf("%v; truncating", err)
+		}
+	}
+	return nil
+}
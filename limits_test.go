@@ -0,0 +1,104 @@
+package emf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// overLimit returns a document exceeding the given CloudWatch limit by one.
+func overLimit(policy LimitPolicy, limit string) CloudWatchMetric {
+	m := NewMetric("NS", WithLimitPolicy(policy))
+	switch limit {
+	case "metrics":
+		for i := 0; i <= MaxMetrics; i++ {
+			m.AddMetric(fmt.Sprintf("M%03d", i), Count, 1)
+		}
+	case "values":
+		for i := 0; i <= MaxValuesPerMetric; i++ {
+			m.AddMetric("Latency", Milliseconds, float64(i))
+		}
+	case "sets":
+		m.AddMetric("Latency", Milliseconds, 1)
+		for i := 0; i <= MaxDimensionSets; i++ {
+			m.AddDimensionSet(map[string]string{fmt.Sprintf("Key%02d", i): "v"})
+		}
+	case "keys":
+		m.AddMetric("Latency", Milliseconds, 1)
+		for i := 0; i <= MaxDimensionKeys; i++ {
+			m.AddDimension(fmt.Sprintf("Key%d", i), "v")
+		}
+	}
+	return m
+}
+
+// resolvedSizes returns the number of metrics, values of the Latency or
+// first metric, dimension sets and keys of the first set of d.
+func resolvedSizes(d ResolvedDocument) (metrics, values, sets, keys int) {
+	dir := d.Directives[0]
+	metrics, sets, keys = len(dir.Metrics), len(dir.Dimensions), len(dir.Dimensions[0])
+	values = 1
+	if v, ok := d.Root[dir.Metrics[0].Name].([]float64); ok {
+		values = len(v)
+	}
+	return metrics, values, sets, keys
+}
+
+var limitKinds = map[string]error{
+	"metrics": ErrTooManyMetrics,
+	"values":  ErrTooManyValues,
+	"sets":    ErrTooManyDimensions,
+	"keys":    ErrTooManyDimensions,
+}
+
+func TestPolicyError(t *testing.T) {
+	for limit, kind := range limitKinds {
+		m := overLimit(PolicyError, limit)
+		if err := m.Validate(); !errors.Is(err, kind) {
+			t.Errorf("%s: Validate() = %v, want %v", limit, err, kind)
+		}
+		if _, err := m.MarshalJSON(); !errors.Is(err, kind) {
+			t.Errorf("%s: MarshalJSON() = %v, want %v", limit, err, kind)
+		}
+	}
+}
+
+func TestPolicyTruncate(t *testing.T) {
+	for limit := range limitKinds {
+		m := overLimit(PolicyTruncate, limit)
+		if err := m.Validate(); err != nil {
+			t.Errorf("%s: Validate() = %v", limit, err)
+		}
+		if _, err := m.MarshalJSON(); err != nil {
+			t.Errorf("%s: MarshalJSON() = %v", limit, err)
+		}
+		metrics, values, sets, keys := resolvedSizes(m.Resolve())
+		if metrics > MaxMetrics || values > MaxValuesPerMetric || sets > MaxDimensionSets || keys > MaxDimensionKeys {
+			t.Errorf("%s: emitted %d metrics, %d values, %d sets, %d keys", limit, metrics, values, sets, keys)
+		}
+	}
+}
+
+func TestPolicyWarn(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	for limit := range limitKinds {
+		buf.Reset()
+		m := overLimit(PolicyWarn, limit)
+		if _, err := m.MarshalJSON(); err != nil {
+			t.Errorf("%s: MarshalJSON() = %v", limit, err)
+		}
+		if !strings.Contains(buf.String(), "exceed") || !strings.Contains(buf.String(), "truncating") {
+			t.Errorf("%s: logged %q, want a truncation warning", limit, buf.String())
+		}
+		metrics, values, sets, keys := resolvedSizes(m.Resolve())
+		if metrics > MaxMetrics || values > MaxValuesPerMetric || sets > MaxDimensionSets || keys > MaxDimensionKeys {
+			t.Errorf("%s: emitted %d metrics, %d values, %d sets, %d keys", limit, metrics, values, sets, keys)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package emf
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"time"
+)
+
+// WithAlwaysArrayValues serializes every metric's values as a JSON array,
+// even when there is only one value. By default a single value is written
+// as a bare number, which CloudWatch accepts and which keeps documents
+// small; use this for downstream consumers that only handle arrays.
+func WithAlwaysArrayValues() Option {
+	return func(c *Config) {
+		c.AlwaysArrayValues = true
+	}
+}
+
+// WithSortedValues sorts the values of each metric in ascending order when
+// the document is marshalled, for consumers that expect sorted input. The
+// counts of weighted values are reordered with their values. The recorded
+// values are not changed. By default values are written in the order they
+// were recorded.
+func WithSortedValues() Option {
+	return func(c *Config) {
+		c.SortedValues = true
+	}
+}
+
+// sortedValues returns copies of values and, if it is not nil, of the
+// parallel counts, sorted by value.
+func sortedValues(values, counts []float64) ([]float64, []float64) {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return values[idx[i]] < values[idx[j]] })
+	sv := make([]float64, len(values))
+	for i, k := range idx {
+		sv[i] = values[k]
+	}
+	if counts == nil {
+		return sv, nil
+	}
+	sc := make([]float64, len(counts))
+	for i, k := range idx {
+		sc[i] = counts[k]
+	}
+	return sv, sc
+}
+
+// WithValuesNamespace nests all metric values under a single root object
+// named field instead of writing them at the root, and refers to them in the
+// metric directive by their dotted path, for example "metrics.Latency".
+// This isolates metric values from properties in strict log schemas, but it
+// changes what CloudWatch extracts: the metric names become the dotted paths,
+// so existing dashboards and alarms must be updated. By default values are
+// written flat at the root.
+func WithValuesNamespace(field string) Option {
+	return func(c *Config) {
+		c.ValuesNamespace = field
+	}
+}
+
+// WithoutDimensionMirroring leaves dimension values out of the document
+// root, listing only the dimension keys in the metric directive.
+//
+// CloudWatch reads dimension values from the root, so metrics in such a
+// document are not extracted at all: the document is only useful as a
+// compact log record, much like a dry run. Use it only in setups that
+// aggregate the raw log events by other means. By default dimension values
+// are mirrored to the root.
+func WithoutDimensionMirroring() Option {
+	return func(c *Config) {
+		c.DisableDimensionMirroring = true
+	}
+}
+
+// WithDropZeroMetrics leaves out metrics whose values are all zero when the
+// document is marshalled. This saves the cost of emitting noise metrics,
+// but an alarm on a dropped metric sees missing data rather than zero, so
+// "treat missing data" settings must account for it. By default all-zero
+// metrics are kept.
+func WithDropZeroMetrics() Option {
+	return func(c *Config) {
+		c.DropZeroMetrics = true
+	}
+}
+
+// WithUnitProperties adds a "<name>.unit" property holding the unit of each
+// metric, for example "Latency.unit": "Milliseconds", so that CloudWatch
+// Logs Insights queries over the raw document can see units. Metrics without
+// a unit get no property. By default units appear only in the metric
+// directive.
+func WithUnitProperties() Option {
+	return func(c *Config) {
+		c.UnitProperties = true
+	}
+}
+
+// MarshalJSON encodes the document in Embedded Metric Format. Limits that
+// CloudWatch enforces are handled according to the document's LimitPolicy;
+// unless it is PolicyError, at most 150 metrics, 100 values per metric, 30
+// dimension sets and 9 keys per set are emitted. Metrics holding NaN or
+// infinite values cannot be encoded and make MarshalJSON fail.
+//
+// The "_aws" member is written first, followed by the properties and
+// dimension values in lexical order and then the metric values in lexical
+// order; see WithMemberOrder.
+func (m *CloudWatchMetric) MarshalJSON() ([]byte, error) {
+	if err := m.checkEncodable(); err != nil {
+		return nil, err
+	}
+	return marshalRoot(m.document(m.effectiveTimestamp()))
+}
+
+// checkEncodable applies the limit policy and returns an error if the
+// document cannot be encoded.
+func (m *CloudWatchMetric) checkEncodable() error {
+	if err := m.applyLimitPolicy(); err != nil {
+		return err
+	}
+	if err := m.checkTemplates(); err != nil {
+		return err
+	}
+	return m.checkFinite()
+}
+
+// Write marshals the document and writes it to w as a single
+// newline-terminated line, the form CloudWatch Logs expects. Statistic sets
+// with timestamps of their own are written as additional lines.
+func (m *CloudWatchMetric) Write(w io.Writer) error {
+	lines, err := m.marshalLines()
+	if err != nil {
+		return err
+	}
+	for _, b := range lines {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAt is like Write but stamps the document with t, for example the
+// end of an aggregation window, instead of its own timestamp. The
+// document's timestamp is left unchanged.
+func (m *CloudWatchMetric) WriteAt(w io.Writer, t time.Time) error {
+	c := m.clone()
+	c.timestamp = t
+	return c.Write(w)
+}
+
+// EmitVerbose validates the document, writes it to w as Write does and
+// returns the exact newline-terminated bytes written, for callers that need
+// to log or hash what was emitted. Nothing is written if validation fails.
+func (m *CloudWatchMetric) EmitVerbose(w io.Writer) ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	lines, err := m.marshalLines()
+	if err != nil {
+		return nil, err
+	}
+	b := bytes.Join(lines, nil)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// EmitTo marshals the document and hands it to s, one call per document if
+// statistic sets with timestamps of their own split it.
+func (m *CloudWatchMetric) EmitTo(s Sink) error {
+	lines, err := m.marshalLines()
+	if err != nil {
+		return err
+	}
+	for _, b := range lines {
+		if err := s.Emit(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalLines returns the newline-terminated encodings of the documents m
+// is written as.
+func (m *CloudWatchMetric) marshalLines() ([][]byte, error) {
+	docs := m.split()
+	lines := make([][]byte, 0, len(docs))
+	for _, doc := range docs {
+		b, err := doc.marshalLine()
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, b)
+	}
+	return lines, nil
+}
+
+// marshalLine returns the newline-terminated encoding of the document.
+func (m *CloudWatchMetric) marshalLine() ([]byte, error) {
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// effectiveTimestamp returns the configured timestamp, or if unset the
+// AWS_EMF_TEST_TIMESTAMP time or now.
+func (m *CloudWatchMetric) effectiveTimestamp() time.Time {
+	return effectiveTime(m.timestamp)
+}
+
+// effectiveTime returns t, or if t is zero the AWS_EMF_TEST_TIMESTAMP time
+// or now.
+func effectiveTime(t time.Time) time.Time {
+	if !t.IsZero() {
+		return t
+	}
+	if ts, ok := testTimestamp(); ok {
+		return ts
+	}
+	return time.Now()
+}
+
+// document builds the JSON object for the document, with its members in
+// the configured order.
+func (m *CloudWatchMetric) document(ts time.Time) interface{} {
+	return m.orderedDocument(m.encodingModel(ts))
+}
+
+// encodingModel returns the model the document with timestamp ts is
+// encoded from, with the number style applied.
+func (m *CloudWatchMetric) encodingModel(ts time.Time) ResolvedDocument {
+	d := m.resolve(ts)
+	if m.cfg.NumberStyle == NumberFloat {
+		d.forceFloats(m.cfg.ValuesNamespace)
+	}
+	return d
+}
+
+// fill sets the dimension and metric value members of the document with
+// timestamp ts in root and returns the metric directive describing them.
+func (m *CloudWatchMetric) fill(root map[string]interface{}, ts time.Time) ResolvedDirective {
+	sets := m.emittedDimensionSets()
+	limit := MaxDimensionSets
+	if m.aggregateSet {
+		limit--
+	}
+	if len(sets) > limit {
+		sets = sets[:limit]
+	}
+	dimensions := make([][]string, 0, len(sets)+1)
+	for _, set := range sets {
+		keys := sortedKeys(set)
+		if len(keys) > MaxDimensionKeys {
+			keys = keys[:MaxDimensionKeys]
+		}
+		if !m.cfg.DisableDimensionMirroring {
+			for _, k := range keys {
+				root[k] = m.dimensionValue(set[k])
+			}
+		}
+		dimensions = append(dimensions, keys)
+	}
+	if m.aggregateSet || len(dimensions) == 0 {
+		dimensions = append(dimensions, []string{})
+	}
+
+	names := m.emittedMetricNames(ts)
+	valuesRoot, prefix := root, ""
+	if m.cfg.ValuesNamespace != "" {
+		valuesRoot = make(map[string]interface{}, len(names))
+		root[m.cfg.ValuesNamespace] = valuesRoot
+		prefix = m.cfg.ValuesNamespace + "."
+	}
+	definitions := make([]ResolvedMetric, 0, len(names))
+	for _, name := range names {
+		mt := m.metrics[name]
+		values := mt.values
+		if r, ok := m.cfg.Clamps[name]; ok {
+			var clamped int
+			values, clamped = r.clamp(values)
+			if m.cfg.CountClamped && clamped > 0 {
+				root[prefix+name+".clamped"] = clamped
+			}
+		}
+		if reduce, ok := m.cfg.Reducers[name]; ok && mt.counts == nil && len(values) > 1 {
+			values = []float64{reduce(values)}
+		}
+		if len(values) > MaxValuesPerMetric {
+			values = values[:MaxValuesPerMetric]
+		}
+		var counts []float64
+		if mt.counts != nil {
+			counts = mt.counts[:len(values)]
+		}
+		if m.cfg.SortedValues {
+			values, counts = sortedValues(values, counts)
+		}
+		if set, ok := mt.statisticSet(ts); ok {
+			valuesRoot[name] = set
+		} else if counts != nil {
+			valuesRoot[name] = WeightedValues{Values: values, Counts: counts}
+		} else if len(values) == 1 && !m.cfg.AlwaysArrayValues {
+			valuesRoot[name] = values[0]
+		} else {
+			valuesRoot[name] = values
+		}
+		if m.cfg.UnitProperties && mt.unit != "" {
+			root[prefix+name+".unit"] = string(mt.unit)
+		}
+		definitions = append(definitions, ResolvedMetric{Name: prefix + name, Unit: mt.unit})
+	}
+
+	return ResolvedDirective{
+		Namespace:  m.namespace,
+		Dimensions: dimensions,
+		Metrics:    definitions,
+	}
+}
+
+// emittedMetricNames returns the names of the metrics written in the
+// document with timestamp ts, in lexical order: those holding values or a
+// statistic set for ts and not dropped by WithDropZeroMetrics, up to
+// MaxMetrics.
+func (m *CloudWatchMetric) emittedMetricNames(ts time.Time) []string {
+	names := make([]string, 0, len(m.metrics))
+	for name, mt := range m.metrics {
+		if len(mt.values) == 0 && !hasStatSetAt(mt, ts) {
+			continue
+		}
+		if m.cfg.DropZeroMetrics && len(mt.statSets) == 0 && allZero(mt.values) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > MaxMetrics {
+		names = names[:MaxMetrics]
+	}
+	return names
+}
+
+// sortedKeys returns the keys of set in lexical order.
+func sortedKeys(set map[string]string) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// allZero reports whether every value is zero.
+func allZero(values []float64) bool {
+	for _, v := range values {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package emf
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+// FuzzMarshal builds documents from random names, values and counts and
+// checks that every document written is valid JSON carrying an "_aws"
+// member, and that marshalling fails only on non-finite values.
+func FuzzMarshal(f *testing.F) {
+	f.Add("NS", "Service", "api", "Latency", 1.5, uint8(3), "RequestId", false)
+	f.Add("", "\xff", " ", "", math.NaN(), uint8(200), "_aws", true)
+	f.Add("ünï/cødé", "Ключ", "値", "métrique", math.Inf(1), uint8(0), "{", true)
+	f.Fuzz(func(t *testing.T, ns, dimKey, dimValue, name string, v float64, n uint8, prop string, weighted bool) {
+		m := NewMetric(ns)
+		m.AddDimension(dimKey, dimValue)
+		m.AddProperty(prop, dimValue)
+		m.AddTemplatedDimension(dimKey+"T", "{"+prop+"}")
+		if weighted {
+			m.AddHistogram(name+"H", Count, []float64{v, v * 2}, int(n%10)+1)
+		}
+		for i := 0; i < int(n); i++ {
+			m.AddMetric(name, Unit(prop), v+float64(i))
+		}
+		m.AddStatisticSet(name+"S", Count, StatisticSet{Min: v, Max: v, Sum: v, SampleCount: 1})
+		m.AddMetricForDimensions(map[string]string{dimKey: dimValue}, name+"D", Count, v)
+
+		finite := !math.IsNaN(v) && !math.IsInf(v, 0) && !math.IsInf(v*2, 0) && !math.IsInf(v+float64(n), 0)
+		b, err := json.Marshal(&m)
+		if err != nil {
+			if finite || !errors.Is(err, ErrNonFiniteValue) {
+				t.Fatalf("MarshalJSON() = %v", err)
+			}
+			return
+		}
+		checkDocument(t, b)
+		lines, err := m.marshalLines()
+		if err != nil {
+			t.Fatalf("marshalLines() = %v after MarshalJSON succeeded", err)
+		}
+		for _, line := range lines {
+			checkDocument(t, line)
+		}
+	})
+}
+
+// checkDocument fails t unless b is a JSON object with an "_aws" object.
+func checkDocument(t *testing.T, b []byte) {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+	if _, ok := doc["_aws"].(map[string]interface{}); !ok {
+		t.Fatalf("%s: no _aws object", b)
+	}
+}
@@ -0,0 +1,124 @@
+package emf
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// MemberOrder is the order of the root members of a marshalled document.
+// JSON objects are unordered, so it only matters to readers of the raw
+// text, such as golden-file tests and log diffs.
+type MemberOrder int
+
+const (
+	// MemberOrderEnvelopeFirst writes the "_aws" member first, then the
+	// properties, including dimension values, in lexical order, then the
+	// metric values in lexical order. It is the default.
+	MemberOrderEnvelopeFirst MemberOrder = iota
+	// MemberOrderLexical writes every root member, "_aws" included, in
+	// lexical byte order, as encoding/json writes a map.
+	MemberOrderLexical
+)
+
+// WithMemberOrder sets the order of the root members of the marshalled
+// document.
+func WithMemberOrder(order MemberOrder) Option {
+	return func(c *Config) {
+		c.MemberOrder = order
+	}
+}
+
+// rootMember is a member of an orderedObject.
+type rootMember struct {
+	key   string
+	value interface{}
+}
+
+// orderedObject is a JSON object that marshals its members in order.
+type orderedObject []rootMember
+
+// MarshalJSON implements json.Marshaler.
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, mem := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(mem.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(mem.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// marshalRoot encodes a document root returned by orderRoot. An
+// orderedObject is encoded directly: json.Marshal would wrap its errors,
+// such as those of NaN property values, in a *json.MarshalerError naming
+// this package's type.
+func marshalRoot(root interface{}) ([]byte, error) {
+	if obj, ok := root.(orderedObject); ok {
+		return obj.MarshalJSON()
+	}
+	return json.Marshal(root)
+}
+
+// metricMembers adds to members the names of the root members holding the
+// metric values of dirs, nested under the values namespace ns if it is not
+// empty.
+func metricMembers(members map[string]bool, dirs []ResolvedDirective, ns string) {
+	if ns != "" {
+		members[ns] = true
+		return
+	}
+	for _, dir := range dirs {
+		for _, def := range dir.Metrics {
+			members[def.Name] = true
+		}
+	}
+}
+
+// orderRoot returns root, which holds the "_aws" member, as an object
+// written in order. Under MemberOrderEnvelopeFirst, metrics names the
+// members holding metric values.
+func orderRoot(root map[string]interface{}, order MemberOrder, metrics map[string]bool) interface{} {
+	if order == MemberOrderLexical {
+		return root
+	}
+	var props, values []string
+	for k := range root {
+		switch {
+		case k == "_aws":
+		case metrics[k]:
+			values = append(values, k)
+		default:
+			props = append(props, k)
+		}
+	}
+	sort.Strings(props)
+	sort.Strings(values)
+	obj := make(orderedObject, 0, len(root))
+	obj = append(obj, rootMember{"_aws", root["_aws"]})
+	for _, k := range append(props, values...) {
+		obj = append(obj, rootMember{k, root[k]})
+	}
+	return obj
+}
+
+// orderedDocument returns the document d, as built by object, written in
+// the member order of m.
+func (m *CloudWatchMetric) orderedDocument(d ResolvedDocument) interface{} {
+	metrics := make(map[string]bool)
+	metricMembers(metrics, d.Directives, m.cfg.ValuesNamespace)
+	return orderRoot(d.object(), m.cfg.MemberOrder, metrics)
+}
@@ -0,0 +1,108 @@
+package emf
+
+import "sort"
+
+// MergeOption configures Merge.
+type MergeOption func(*mergeConfig)
+
+type mergeConfig struct {
+	inherit bool
+}
+
+// WithoutDimensionInheritance makes Merge keep the dimension sets of the
+// child exactly as they are.
+func WithoutDimensionInheritance() MergeOption {
+	return func(c *mergeConfig) {
+		c.inherit = false
+	}
+}
+
+// Merge adds the metrics of child to m, keeping them under the child's own
+// dimension sets: each child metric is recorded as with
+// AddMetricForDimensions once per dimension set of the child, and the
+// child's own AddMetricForDimensions metrics are carried over likewise. A
+// child without dimension sets contributes metrics without dimensions.
+// Values, value counts and statistic sets are all carried over.
+//
+// By default each child dimension set inherits the default dimensions of m
+// (those added with AddDimension or WithDefaultDimensions) whose keys it
+// does not have, so that merged metrics are dimensioned consistently with
+// the parent; the child's value wins for a key both have. Pass
+// WithoutDimensionInheritance to disable this. Child properties are added
+// to m unless m already has a property of the same name. child is not
+// modified.
+func (m *CloudWatchMetric) Merge(child *CloudWatchMetric, opts ...MergeOption) {
+	cfg := mergeConfig{inherit: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var inherited map[string]string
+	if cfg.inherit && len(m.dimensionSets) > 0 {
+		inherited = m.dimensionSets[0]
+	}
+	dimensions := func(set map[string]string) map[string]string {
+		dims := copyDimensions(set)
+		if dims == nil {
+			dims = make(map[string]string, len(inherited))
+		}
+		for k, v := range inherited {
+			if _, ok := dims[k]; !ok {
+				dims[k] = v
+			}
+		}
+		return dims
+	}
+
+	sets := child.emittedDimensionSets()
+	if len(sets) == 0 {
+		sets = []map[string]string{{}}
+	}
+	for _, set := range sets {
+		m.mergeMetrics(dimensions(set), child.metrics)
+	}
+	for _, s := range child.scoped {
+		m.mergeMetrics(dimensions(s.dims), s.metrics)
+	}
+
+	m.lazyInit()
+	for _, k := range sortedPropertyKeys(child.properties) {
+		if _, ok := m.properties[k]; !ok {
+			m.setProperty(k, child.properties[k])
+		}
+	}
+}
+
+// mergeMetrics adds metrics to the scoped metrics of m recorded under dims.
+func (m *CloudWatchMetric) mergeMetrics(dims map[string]string, metrics map[string]*metric) {
+	for _, key := range sortedMetricNames(metrics) {
+		src := metrics[key]
+		dst := m.scopedMetric(dims, key, src.unit)
+		if src.counts != nil && dst.counts == nil {
+			dst.counts = make([]float64, len(dst.values))
+			for i := range dst.counts {
+				dst.counts[i] = 1
+			}
+		}
+		dst.values = append(dst.values, src.values...)
+		if dst.counts != nil {
+			if src.counts != nil {
+				dst.counts = append(dst.counts, src.counts...)
+			} else {
+				for range src.values {
+					dst.counts = append(dst.counts, 1)
+				}
+			}
+		}
+		dst.statSets = append(dst.statSets, src.statSets...)
+	}
+}
+
+// sortedPropertyKeys returns the keys of props in lexical order.
+func sortedPropertyKeys(props map[string]interface{}) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+package emf
+
+import "sync"
+
+// Counter accumulates a total, such as a number of requests.
+type Counter interface {
+	Add(delta float64)
+}
+
+// Gauge holds a value sampled at a point in time, such as a queue depth.
+type Gauge interface {
+	Set(value float64)
+}
+
+// Histogram records the distribution of individual values, such as
+// latencies.
+type Histogram interface {
+	Observe(value float64)
+}
+
+// Meter creates instruments. Program against it to keep application code
+// independent of this package, for example to substitute a recording fake
+// in tests; DocumentMeter implements it on top of EMF.
+type Meter interface {
+	Counter(name string, unit Unit) Counter
+	Gauge(name string, unit Unit) Gauge
+	Histogram(name string, unit Unit) Histogram
+}
+
+// DocumentMeter is a Meter whose instruments record into a document that
+// Flush emits. A Counter is emitted as its total since the previous flush,
+// a Gauge as its last value and a Histogram as every observed value.
+// Instruments not used since the previous flush are left out. It is safe
+// for concurrent use.
+type DocumentMeter struct {
+	mu  sync.Mutex
+	doc CloudWatchMetric
+}
+
+// NewDocumentMeter returns a DocumentMeter recording into a document
+// created with NewMetric(namespace, opts...).
+func NewDocumentMeter(namespace string, opts ...Option) *DocumentMeter {
+	return &DocumentMeter{doc: NewMetric(namespace, opts...)}
+}
+
+// Counter returns a Counter adding to the named metric with Add.
+func (d *DocumentMeter) Counter(name string, unit Unit) Counter {
+	return meterCounter{d, name, unit}
+}
+
+// Gauge returns a Gauge setting the named metric with SetGauge.
+func (d *DocumentMeter) Gauge(name string, unit Unit) Gauge {
+	return meterGauge{d, name, unit}
+}
+
+// Histogram returns a Histogram appending to the named metric with
+// AddMetric.
+func (d *DocumentMeter) Histogram(name string, unit Unit) Histogram {
+	return meterHistogram{d, name, unit}
+}
+
+// Flush emits what the instruments recorded since the previous flush to s
+// and clears it. Nothing is emitted if nothing was recorded.
+func (d *DocumentMeter) Flush(s Sink) error {
+	d.mu.Lock()
+	snap := d.doc.Snapshot()
+	d.doc.Reset()
+	d.mu.Unlock()
+	if !snap.hasValues() {
+		return nil
+	}
+	return snap.EmitTo(s)
+}
+
+// hasValues reports whether any metric of the document holds values.
+func (m *CloudWatchMetric) hasValues() bool {
+	for _, mt := range m.metrics {
+		if len(mt.values) > 0 || len(mt.statSets) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// record calls fn with the document while holding the lock.
+func (d *DocumentMeter) record(fn func(m *CloudWatchMetric)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&d.doc)
+}
+
+type meterCounter struct {
+	meter *DocumentMeter
+	name  string
+	unit  Unit
+}
+
+func (c meterCounter) Add(delta float64) {
+	c.meter.record(func(m *CloudWatchMetric) {
+		m.lazyInit()
+		if _, ok := m.metrics[c.name]; !ok {
+			m.metrics[c.name] = &metric{unit: c.unit}
+		}
+		m.Add(c.name, delta)
+	})
+}
+
+type meterGauge struct {
+	meter *DocumentMeter
+	name  string
+	unit  Unit
+}
+
+func (g meterGauge) Set(value float64) {
+	g.meter.record(func(m *CloudWatchMetric) {
+		m.SetGauge(g.name, g.unit, value)
+	})
+}
+
+type meterHistogram struct {
+	meter *DocumentMeter
+	name  string
+	unit  Unit
+}
+
+func (h meterHistogram) Observe(value float64) {
+	h.meter.record(func(m *CloudWatchMetric) {
+		m.AddMetric(h.name, h.unit, value)
+	})
+}
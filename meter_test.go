@@ -0,0 +1,110 @@
+package emf
+
+import (
+	"sync"
+	"testing"
+)
+
+// metricUnits returns the unit of every metric declared by doc.
+func metricUnits(t *testing.T, doc map[string]interface{}) map[string]string {
+	t.Helper()
+	aws := doc["_aws"].(map[string]interface{})
+	directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	units := make(map[string]string)
+	for _, m := range directive["Metrics"].([]interface{}) {
+		def := m.(map[string]interface{})
+		units[def["Name"].(string)], _ = def["Unit"].(string)
+	}
+	return units
+}
+
+func TestDocumentMeterFlush(t *testing.T) {
+	var meter Meter = NewDocumentMeter("NS")
+	requests := meter.Counter("Requests", Count)
+	depth := meter.Gauge("QueueDepth", Count)
+	latency := meter.Histogram("Latency", Milliseconds)
+	requests.Add(2)
+	requests.Add(3)
+	depth.Set(1)
+	depth.Set(7)
+	latency.Observe(10)
+	latency.Observe(20)
+
+	sink := &bufferSink{}
+	d := meter.(*DocumentMeter)
+	if err := d.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeDocs(t, sink)
+	if len(docs) != 1 {
+		t.Fatalf("flushed %d documents, want 1", len(docs))
+	}
+	doc := docs[0]
+	if doc["Requests"] != 5.0 {
+		t.Errorf("Requests = %v, want the total 5", doc["Requests"])
+	}
+	if doc["QueueDepth"] != 7.0 {
+		t.Errorf("QueueDepth = %v, want the last value 7", doc["QueueDepth"])
+	}
+	if got, ok := doc["Latency"].([]interface{}); !ok || len(got) != 2 || got[0] != 10.0 || got[1] != 20.0 {
+		t.Errorf("Latency = %v, want [10 20]", doc["Latency"])
+	}
+	units := metricUnits(t, doc)
+	for name, want := range map[string]Unit{"Requests": Count, "QueueDepth": Count, "Latency": Milliseconds} {
+		if units[name] != string(want) {
+			t.Errorf("%s unit = %q, want %q", name, units[name], want)
+		}
+	}
+
+	requests.Add(1)
+	if err := d.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	docs = decodeDocs(t, sink)
+	if len(docs) != 2 {
+		t.Fatalf("flushed %d documents, want 2", len(docs))
+	}
+	if docs[1]["Requests"] != 1.0 {
+		t.Errorf("Requests after a flush = %v, want 1", docs[1]["Requests"])
+	}
+	for _, name := range []string{"QueueDepth", "Latency"} {
+		if _, ok := docs[1][name]; ok {
+			t.Errorf("unused %s was emitted again", name)
+		}
+	}
+}
+
+func TestDocumentMeterFlushSkipsEmpty(t *testing.T) {
+	d := NewDocumentMeter("NS")
+	d.Counter("Requests", Count)
+	sink := &bufferSink{}
+	if err := d.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.docs) != 0 {
+		t.Errorf("flushed %d documents with nothing recorded, want none", len(sink.docs))
+	}
+}
+
+func TestDocumentMeterConcurrentCounter(t *testing.T) {
+	d := NewDocumentMeter("NS")
+	requests := d.Counter("Requests", Count)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				requests.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	sink := &bufferSink{}
+	if err := d.Flush(sink); err != nil {
+		t.Fatal(err)
+	}
+	if docs := decodeDocs(t, sink); len(docs) != 1 || docs[0]["Requests"] != 800.0 {
+		t.Errorf("flushed %v, want Requests 800", docs)
+	}
+}
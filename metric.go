@@ -0,0 +1,267 @@
+package emf
+
+import (
+	"fmt"
+	"time"
+)
+
+// CloudWatchMetric is a single EMF document under construction. It is not
+// safe for concurrent use.
+type CloudWatchMetric struct {
+	namespace     string
+	timestamp     time.Time
+	dimensionSets []map[string]string
+	primarySet    map[string]string
+	properties    map[string]interface{}
+	propertySeq   map[string]uint64
+	nextSeq       uint64
+	metrics       map[string]*metric
+	aggregateSet  bool
+	templates     map[string]string
+	scoped        map[string]*scopedMetrics
+	windowed      map[string]bool
+	cfg           Config
+}
+
+// metric is the recorded state of a single named metric.
+type metric struct {
+	unit   Unit
+	values []float64
+	// counts, if not nil, holds the number of occurrences of each value.
+	counts   []float64
+	statSets []timedStatisticSet
+	// kind is how the metric was created.
+	kind Kind
+	// seen holds the deduplication keys given to RecordOnce since the
+	// values were last cleared.
+	seen map[string]bool
+}
+
+// Option configures a CloudWatchMetric at construction by modifying its
+// Config.
+type Option func(*Config)
+
+// WithTimestamp sets the document timestamp. Without it the time of
+// marshalling is used.
+func WithTimestamp(t time.Time) Option {
+	return func(c *Config) {
+		c.Timestamp = t
+	}
+}
+
+// NewMetric returns an empty document for the given namespace, using
+// DefaultLimitPolicy unless an option says otherwise.
+func NewMetric(namespace string, opts ...Option) CloudWatchMetric {
+	cfg := Config{
+		Namespace:   namespace,
+		LimitPolicy: DefaultLimitPolicy,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return NewMetricFromConfig(cfg)
+}
+
+// Namespace returns the CloudWatch namespace of the document.
+func (m *CloudWatchMetric) Namespace() string {
+	return m.namespace
+}
+
+// SetNamespace replaces the CloudWatch namespace of the document.
+func (m *CloudWatchMetric) SetNamespace(namespace string) {
+	m.namespace = namespace
+}
+
+// Timestamp returns the timestamp the document is emitted with, at the
+// millisecond precision of EMF. If no timestamp was set, it is the time
+// given by AWS_EMF_TEST_TIMESTAMP or else the current time; the document
+// is left without one, so that it is still stamped when it is marshalled.
+func (m *CloudWatchMetric) Timestamp() time.Time {
+	return m.effectiveTimestamp().Truncate(time.Millisecond)
+}
+
+// SetTimestamp sets the document timestamp.
+func (m *CloudWatchMetric) SetTimestamp(t time.Time) {
+	m.timestamp = t
+}
+
+// AddMetric appends value to the named metric. Repeated calls for the same
+// key accumulate values; the unit of the first call is kept. Under
+// WithValueSampling only some values are stored. It returns m so that
+// calls can be chained.
+func (m *CloudWatchMetric) AddMetric(key string, unit Unit, value float64) *CloudWatchMetric {
+	m.lazyInit()
+	mt, ok := m.metrics[key]
+	if !ok {
+		mt = &metric{unit: unit}
+		m.metrics[key] = mt
+	}
+	if !m.sampleValue(key) {
+		return m
+	}
+	mt.values = append(mt.values, value)
+	if mt.counts != nil {
+		mt.counts = append(mt.counts, 1)
+	}
+	return m
+}
+
+// AddToMetrics appends value to each of the named metrics, as AddMetric
+// does, for a measurement that feeds several aggregates such as a
+// per-endpoint and a global latency. It returns m so that calls can be
+// chained.
+func (m *CloudWatchMetric) AddToMetrics(keys []string, unit Unit, value float64) *CloudWatchMetric {
+	for _, key := range keys {
+		m.AddMetric(key, unit, value)
+	}
+	return m
+}
+
+// ReplaceMetric sets the values of the named metric to values, discarding
+// anything previously recorded for it, unlike AddMetric which appends. Use
+// it for gauges recomputed as a whole each cycle; the metric becomes a
+// KindGauge metric.
+func (m *CloudWatchMetric) ReplaceMetric(key string, unit Unit, values ...float64) {
+	m.lazyInit()
+	m.metrics[key] = &metric{unit: unit, kind: KindGauge, values: append([]float64(nil), values...)}
+}
+
+// SetMetricUnit changes the unit of an existing metric without touching its
+// values. It returns an error if no metric has the key.
+func (m *CloudWatchMetric) SetMetricUnit(key string, unit Unit) error {
+	mt, ok := m.metrics[key]
+	if !ok {
+		return fmt.Errorf("emf: no metric %q", key)
+	}
+	mt.unit = unit
+	return nil
+}
+
+// ScaleMetric multiplies every value recorded for the metric by factor, for
+// example 1.0/1024 to turn Bytes into Kilobytes together with SetMetricUnit.
+// It returns an error if no metric has the key.
+func (m *CloudWatchMetric) ScaleMetric(key string, factor float64) error {
+	mt, ok := m.metrics[key]
+	if !ok {
+		return fmt.Errorf("emf: no metric %q", key)
+	}
+	for i := range mt.values {
+		mt.values[i] *= factor
+	}
+	return nil
+}
+
+// AddDimension adds a dimension to the default dimension set. It returns m
+// so that calls can be chained.
+func (m *CloudWatchMetric) AddDimension(key, value string) *CloudWatchMetric {
+	if len(m.dimensionSets) == 0 {
+		m.dimensionSets = append(m.dimensionSets, make(map[string]string))
+	}
+	m.dimensionSets[0][key] = value
+	return m
+}
+
+// AddDimensionSet adds an additional dimension set. Every metric in the
+// document is extracted once per dimension set.
+func (m *CloudWatchMetric) AddDimensionSet(dims map[string]string) {
+	m.dimensionSets = append(m.dimensionSets, copyDimensions(dims))
+}
+
+// AddProperty adds a root-level property that is searchable in CloudWatch
+// Logs Insights but not extracted as a metric.
+func (m *CloudWatchMetric) AddProperty(key string, value interface{}) {
+	m.lazyInit()
+	m.setProperty(key, value)
+}
+
+// AddProperties adds each entry of props as a root-level property. It
+// returns m so that calls can be chained.
+func (m *CloudWatchMetric) AddProperties(props map[string]interface{}) *CloudWatchMetric {
+	m.lazyInit()
+	for _, k := range sortedPropertyKeys(props) {
+		m.setProperty(k, props[k])
+	}
+	return m
+}
+
+// setProperty sets a property and records it as the most recently set, for
+// WithMaxProperties.
+func (m *CloudWatchMetric) setProperty(key string, value interface{}) {
+	m.properties[key] = value
+	m.propertySeq[key] = m.nextSeq
+	m.nextSeq++
+}
+
+// lazyInit makes the zero CloudWatchMetric usable.
+func (m *CloudWatchMetric) lazyInit() {
+	if m.properties == nil {
+		m.properties = make(map[string]interface{})
+	}
+	if m.propertySeq == nil {
+		m.propertySeq = make(map[string]uint64)
+	}
+	if m.metrics == nil {
+		m.metrics = make(map[string]*metric)
+	}
+}
+
+// clone returns a deep copy of the document.
+func (m *CloudWatchMetric) clone() CloudWatchMetric {
+	c := *m
+	c.dimensionSets = make([]map[string]string, len(m.dimensionSets))
+	for i, set := range m.dimensionSets {
+		c.dimensionSets[i] = copyDimensions(set)
+	}
+	if m.primarySet != nil {
+		c.primarySet = copyDimensions(m.primarySet)
+	}
+	if m.templates != nil {
+		c.templates = copyDimensions(m.templates)
+	}
+	if m.scoped != nil {
+		c.scoped = make(map[string]*scopedMetrics, len(m.scoped))
+		for sig, s := range m.scoped {
+			cs := &scopedMetrics{dims: copyDimensions(s.dims), metrics: make(map[string]*metric, len(s.metrics))}
+			for k, mt := range s.metrics {
+				cs.metrics[k] = mt.copy()
+			}
+			c.scoped[sig] = cs
+		}
+	}
+	if m.windowed != nil {
+		c.windowed = make(map[string]bool, len(m.windowed))
+		for k := range m.windowed {
+			c.windowed[k] = true
+		}
+	}
+	c.properties = make(map[string]interface{}, len(m.properties))
+	for k, v := range m.properties {
+		c.properties[k] = v
+	}
+	c.propertySeq = make(map[string]uint64, len(m.propertySeq))
+	for k, n := range m.propertySeq {
+		c.propertySeq[k] = n
+	}
+	c.metrics = make(map[string]*metric, len(m.metrics))
+	for k, mt := range m.metrics {
+		c.metrics[k] = mt.copy()
+	}
+	return c
+}
+
+// copy returns a deep copy of the metric.
+func (mt *metric) copy() *metric {
+	cm := *mt
+	cm.values = append([]float64(nil), mt.values...)
+	if mt.counts != nil {
+		cm.counts = append([]float64(nil), mt.counts...)
+	}
+	cm.statSets = append([]timedStatisticSet(nil), mt.statSets...)
+	if mt.seen != nil {
+		cm.seen = make(map[string]bool, len(mt.seen))
+		for k := range mt.seen {
+			cm.seen[k] = true
+		}
+	}
+	return &cm
+}
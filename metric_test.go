@@ -0,0 +1,46 @@
+package emf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplaceMetric(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("QueueDepth", Count, 1)
+	m.AddMetric("QueueDepth", Count, 2)
+	m.ReplaceMetric("QueueDepth", Count, 7, 8, 9)
+
+	got := m.Resolve().Root["QueueDepth"]
+	if want := []float64{7, 8, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("QueueDepth = %v, want %v", got, want)
+	}
+	if kind, _ := m.MetricKind("QueueDepth"); kind != KindGauge {
+		t.Errorf("MetricKind() = %v, want KindGauge", kind)
+	}
+
+	m.ReplaceMetric("QueueDepth", Count, 3)
+	if got := m.Resolve().Root["QueueDepth"]; got != 3.0 {
+		t.Errorf("QueueDepth = %v after a second replace, want 3", got)
+	}
+}
+
+func TestReplaceMetricCopiesValues(t *testing.T) {
+	values := []float64{1, 2}
+	m := NewMetric("NS")
+	m.ReplaceMetric("QueueDepth", Count, values...)
+	values[0] = 99
+	if got := m.Resolve().Root["QueueDepth"]; !reflect.DeepEqual(got, []float64{1, 2}) {
+		t.Errorf("QueueDepth = %v, want [1 2]", got)
+	}
+}
+
+func TestTimestampDoesNotStamp(t *testing.T) {
+	m := NewMetric("NS")
+	if got := m.Timestamp(); got.IsZero() {
+		t.Fatal("Timestamp() is zero without a timestamp set")
+	}
+	if !m.timestamp.IsZero() {
+		t.Errorf("Timestamp() stored %v as the document timestamp", m.timestamp)
+	}
+}
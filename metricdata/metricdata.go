@@ -0,0 +1,142 @@
+// Package metricdata converts EMF documents to CloudWatch PutMetricData
+// input, for low-volume metrics that should be pushed to the CloudWatch
+// Metrics API directly instead of being extracted from logs. Each metric
+// becomes one datum per dimension set, carrying its values and counts, or
+// its statistic set as StatisticValues, with dimension values taken from
+// the document's members:
+//
+//	client := cloudwatch.NewFromConfig(cfg)
+//	for _, in := range metricdata.Inputs(m) {
+//		if _, err := client.PutMetricData(ctx, in); err != nil {
+//			return err
+//		}
+//	}
+//
+// Properties have no counterpart in PutMetricData and are dropped.
+package metricdata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// MaxDatumsPerCall is the number of datums Inputs puts in a single
+// PutMetricData call.
+const MaxDatumsPerCall = 20
+
+// ToMetricData returns one datum per metric and dimension set of every
+// document m is written as, scoped metrics included, as CloudWatch would
+// extract them from the EMF documents. Datums of a metric without
+// dimensions carry no dimensions.
+func ToMetricData(m *emf.CloudWatchMetric) []types.MetricDatum {
+	var data []types.MetricDatum
+	for _, doc := range m.ResolveAll() {
+		for _, directive := range doc.Directives {
+			data = append(data, directiveData(doc, directive)...)
+		}
+	}
+	return data
+}
+
+// Inputs returns the PutMetricData calls needed to push m: one per
+// namespace and at most MaxDatumsPerCall datums each.
+func Inputs(m *emf.CloudWatchMetric) []*cloudwatch.PutMetricDataInput {
+	var namespaces []string
+	byNamespace := make(map[string][]types.MetricDatum)
+	for _, doc := range m.ResolveAll() {
+		for _, directive := range doc.Directives {
+			ns := directive.Namespace
+			if _, ok := byNamespace[ns]; !ok {
+				namespaces = append(namespaces, ns)
+			}
+			byNamespace[ns] = append(byNamespace[ns], directiveData(doc, directive)...)
+		}
+	}
+	var inputs []*cloudwatch.PutMetricDataInput
+	for _, ns := range namespaces {
+		data := byNamespace[ns]
+		for len(data) > 0 {
+			n := len(data)
+			if n > MaxDatumsPerCall {
+				n = MaxDatumsPerCall
+			}
+			inputs = append(inputs, &cloudwatch.PutMetricDataInput{
+				Namespace:  aws.String(ns),
+				MetricData: data[:n:n],
+			})
+			data = data[n:]
+		}
+	}
+	return inputs
+}
+
+// directiveData returns the datums described by a single metric directive.
+func directiveData(doc emf.ResolvedDocument, directive emf.ResolvedDirective) []types.MetricDatum {
+	var data []types.MetricDatum
+	for _, def := range directive.Metrics {
+		values, counts, stats := lookupValues(doc.Root, def.Name)
+		if len(values) == 0 && stats == nil {
+			continue
+		}
+		unit := types.StandardUnitNone
+		if def.Unit != "" {
+			unit = types.StandardUnit(def.Unit)
+		}
+		for _, keys := range directive.Dimensions {
+			dims := make([]types.Dimension, 0, len(keys))
+			for _, k := range keys {
+				dims = append(dims, types.Dimension{
+					Name:  aws.String(k),
+					Value: aws.String(fmt.Sprint(doc.Root[k])),
+				})
+			}
+			data = append(data, types.MetricDatum{
+				MetricName:      aws.String(def.Name),
+				Unit:            unit,
+				Dimensions:      dims,
+				Values:          values,
+				Counts:          counts,
+				StatisticValues: stats,
+				Timestamp:       aws.Time(doc.Timestamp),
+			})
+		}
+	}
+	return data
+}
+
+// lookupValues returns the values, value counts or statistic set of the
+// metric name in root, following a dotted path into nested objects if name
+// is not a root member.
+func lookupValues(root map[string]interface{}, name string) ([]float64, []float64, *types.StatisticSet) {
+	v, ok := root[name]
+	if !ok {
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			if nested, ok := root[name[:i]].(map[string]interface{}); ok {
+				return lookupValues(nested, name[i+1:])
+			}
+		}
+		return nil, nil, nil
+	}
+	switch v := v.(type) {
+	case float64:
+		return []float64{v}, nil, nil
+	case []float64:
+		return append([]float64(nil), v...), nil, nil
+	case emf.WeightedValues:
+		return append([]float64(nil), v.Values...), append([]float64(nil), v.Counts...), nil
+	case emf.StatisticSet:
+		return nil, nil, &types.StatisticSet{
+			Minimum:     aws.Float64(v.Min),
+			Maximum:     aws.Float64(v.Max),
+			Sum:         aws.Float64(v.Sum),
+			SampleCount: aws.Float64(v.SampleCount),
+		}
+	}
+	return nil, nil, nil
+}
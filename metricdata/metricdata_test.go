@@ -0,0 +1,38 @@
+package metricdata
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+func TestToMetricDataIncludesScopedMetrics(t *testing.T) {
+	m := emf.NewMetric("NS")
+	m.AddMetric("Latency", emf.Milliseconds, 5)
+	m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Requests", emf.Count, 1)
+
+	data := ToMetricData(&m)
+	if len(data) != 2 {
+		t.Fatalf("ToMetricData() returned %d datums, want 2", len(data))
+	}
+	d := data[1]
+	if aws.ToString(d.MetricName) != "Requests" || len(d.Dimensions) != 1 || aws.ToString(d.Dimensions[0].Value) != "Get" {
+		t.Errorf("scoped datum = %+v, want Requests under Operation=Get", d)
+	}
+}
+
+func TestInputsGroupsDocumentsByNamespace(t *testing.T) {
+	m := emf.NewMetric("NS")
+	m.AddMetric("Latency", emf.Milliseconds, 5)
+	m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Requests", emf.Count, 1)
+
+	inputs := Inputs(&m)
+	if len(inputs) != 1 || len(inputs[0].MetricData) != 2 {
+		t.Fatalf("Inputs() = %d calls, want one call of 2 datums", len(inputs))
+	}
+	if aws.ToString(inputs[0].Namespace) != "NS" {
+		t.Errorf("Namespace = %q, want NS", aws.ToString(inputs[0].Namespace))
+	}
+}
@@ -0,0 +1,91 @@
+package emf
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// NumberStyle is how metric values are written in the document.
+type NumberStyle int
+
+const (
+	// NumberNatural writes each value in its shortest form: integral
+	// values, such as counts, without a decimal point (5) and other values
+	// as they are (5.25). It is the default.
+	NumberNatural NumberStyle = iota
+	// NumberFloat writes every value with a decimal point or exponent, so
+	// that integral values are written as 5.0.
+	NumberFloat
+)
+
+// WithNumberStyle sets how metric values, value counts and statistic set
+// members are written, for downstream parsers that are strict about
+// number types. Properties are written as they are.
+func WithNumberStyle(style NumberStyle) Option {
+	return func(c *Config) {
+		c.NumberStyle = style
+	}
+}
+
+// floatNumber is a float64 that marshals with a decimal point even when it
+// is integral.
+type floatNumber float64
+
+// MarshalJSON implements json.Marshaler.
+func (f floatNumber) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(float64(f))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.ContainsAny(b, ".eE") {
+		b = append(b, ".0"...)
+	}
+	return b, nil
+}
+
+// floatNumbers converts values to floatNumbers.
+func floatNumbers(values []float64) []floatNumber {
+	out := make([]floatNumber, len(values))
+	for i, v := range values {
+		out[i] = floatNumber(v)
+	}
+	return out
+}
+
+// forceFloats replaces the metric values in d.Root, nested under the
+// values namespace ns if it is not empty, with values that are written
+// under NumberFloat. The result no longer holds the types documented on
+// Root, so it is only used for encoding.
+func (d ResolvedDocument) forceFloats(ns string) {
+	parent := d.Root
+	if ns != "" {
+		parent, _ = d.Root[ns].(map[string]interface{})
+	}
+	for _, dir := range d.Directives {
+		for _, def := range dir.Metrics {
+			name := def.Name
+			if ns != "" {
+				name = strings.TrimPrefix(name, ns+".")
+			}
+			switch v := parent[name].(type) {
+			case float64:
+				parent[name] = floatNumber(v)
+			case []float64:
+				parent[name] = floatNumbers(v)
+			case WeightedValues:
+				parent[name] = struct {
+					Values []floatNumber `json:"Values"`
+					Counts []floatNumber `json:"Counts"`
+				}{floatNumbers(v.Values), floatNumbers(v.Counts)}
+			case StatisticSet:
+				parent[name] = struct {
+					Min         floatNumber `json:"Min"`
+					Max         floatNumber `json:"Max"`
+					Sum         floatNumber `json:"Sum"`
+					SampleCount floatNumber `json:"SampleCount"`
+				}{floatNumber(v.Min), floatNumber(v.Max), floatNumber(v.Sum), floatNumber(v.SampleCount)}
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package emf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberNatural(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("Requests", Count, 5)
+	m.AddMetric("Latency", Milliseconds, 5.25)
+	b, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"Requests":5}`, `"Latency":5.25,`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("%s does not contain %s", b, want)
+		}
+	}
+}
+
+func TestNumberFloat(t *testing.T) {
+	for _, ns := range []string{"", "Values"} {
+		m := NewMetric("NS", WithNumberStyle(NumberFloat), WithValuesNamespace(ns))
+		m.AddMetric("Requests", Count, 5)
+		m.AddMetric("Latency", Milliseconds, 5.25)
+		m.AddMetric("Sizes", Bytes, 1)
+		m.AddMetric("Sizes", Bytes, 2)
+		m.AddStatisticSet("Stat", Count, StatisticSet{Min: 1, Max: 3, Sum: 4, SampleCount: 2})
+		m.AddProperty("Retries", 3)
+		b, err := m.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			`"Requests":5.0`,
+			`"Latency":5.25`,
+			`"Sizes":[1.0,2.0]`,
+			`"Stat":{"Min":1.0,"Max":3.0,"Sum":4.0,"SampleCount":2.0}`,
+			`"Retries":3`,
+		} {
+			if !strings.Contains(string(b), want) {
+				t.Errorf("namespace %q: %s does not contain %s", ns, b, want)
+			}
+		}
+	}
+}
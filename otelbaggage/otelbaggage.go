@@ -0,0 +1,52 @@
+// Package otelbaggage extracts OpenTelemetry baggage from a context for
+// emf.WithContextProperties, so that values propagated with a request,
+// such as a tenant or a deployment, are emitted as properties of every
+// document emitted in its context and can be searched in CloudWatch Logs
+// Insights:
+//
+//	emitter := emf.NewEmitter(sink, emf.WithContextProperties(otelbaggage.ExtractPrefixed("baggage.")))
+//	emitter.EmitContext(ctx, &m)
+//
+// Baggage becomes properties, not dimensions, since its values are set by
+// callers and could otherwise create any number of metrics.
+package otelbaggage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+var _ emf.ContextExtractor = Extract
+
+// Extract returns the members of the baggage of ctx, keyed by member key.
+// Member properties are not included.
+func Extract(ctx context.Context) map[string]string {
+	members := baggage.FromContext(ctx).Members()
+	if len(members) == 0 {
+		return nil
+	}
+	props := make(map[string]string, len(members))
+	for _, m := range members {
+		props[m.Key()] = m.Value()
+	}
+	return props
+}
+
+// ExtractPrefixed is like Extract but prefixes each key, for example with
+// "baggage.", to keep baggage apart from other properties.
+func ExtractPrefixed(prefix string) emf.ContextExtractor {
+	return func(ctx context.Context) map[string]string {
+		props := Extract(ctx)
+		if len(props) == 0 {
+			return nil
+		}
+		prefixed := make(map[string]string, len(props))
+		for k, v := range props {
+			prefixed[prefix+k] = v
+		}
+		return prefixed
+	}
+}
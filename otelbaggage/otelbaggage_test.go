@@ -0,0 +1,68 @@
+package otelbaggage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// withBaggage returns a context carrying the given baggage members.
+func withBaggage(t *testing.T, kv ...string) context.Context {
+	t.Helper()
+	var members []baggage.Member
+	for i := 0; i < len(kv); i += 2 {
+		m, err := baggage.NewMember(kv[i], kv[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, m)
+	}
+	b, err := baggage.New(members...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return baggage.ContextWithBaggage(context.Background(), b)
+}
+
+func TestExtract(t *testing.T) {
+	if got := Extract(context.Background()); got != nil {
+		t.Errorf("Extract() without baggage = %v, want nil", got)
+	}
+	got := Extract(withBaggage(t, "tenant", "t-1", "deployment", "blue"))
+	if len(got) != 2 || got["tenant"] != "t-1" || got["deployment"] != "blue" {
+		t.Errorf("Extract() = %v, want tenant and deployment", got)
+	}
+	if got := ExtractPrefixed("baggage.")(context.Background()); got != nil {
+		t.Errorf("ExtractPrefixed() without baggage = %v, want nil", got)
+	}
+}
+
+func TestBaggageBecomesProperties(t *testing.T) {
+	var buf bytes.Buffer
+	e := emf.NewEmitter(emf.NewWriterSink(&buf), emf.WithContextProperties(ExtractPrefixed("baggage.")))
+	m := emf.NewMetric("NS")
+	m.AddDimension("Service", "api")
+	m.AddMetric("Requests", emf.Count, 1)
+	if err := e.EmitContext(withBaggage(t, "tenant", "t-1"), &m); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["baggage.tenant"] != "t-1" {
+		t.Errorf("baggage.tenant = %v, want t-1", doc["baggage.tenant"])
+	}
+	for _, set := range doc["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})["Dimensions"].([]interface{}) {
+		for _, key := range set.([]interface{}) {
+			if key == "baggage.tenant" {
+				t.Error("baggage became a dimension")
+			}
+		}
+	}
+}
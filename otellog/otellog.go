@@ -0,0 +1,173 @@
+// Package otellog converts EMF documents to OpenTelemetry log records, so
+// that they can flow through an OpenTelemetry Collector pipeline to
+// CloudWatch Logs. The record body is the document itself, so that an
+// exporter writing bodies to CloudWatch Logs writes the EMF the library
+// produced, and the properties are repeated as attributes that processors
+// can match on without parsing the body:
+//
+//	sink := otellog.NewSink(provider.Logger("emf"))
+//	err := m.EmitTo(sink)
+//
+// Records converts a metric without emitting it, for callers that batch or
+// enrich records themselves.
+package otellog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// Records returns one log record per document m is written as, as
+// FromDocument converts them.
+func Records(m *emf.CloudWatchMetric) ([]log.Record, error) {
+	var docs collector
+	if err := m.EmitTo(&docs); err != nil {
+		return nil, err
+	}
+	records := make([]log.Record, 0, len(docs))
+	for _, doc := range docs {
+		r, err := FromDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// FromDocument converts a single encoded EMF document into a log record.
+// The body is the whole document as a map, "_aws" member included, so
+// that encoding the body as JSON reproduces the document: integers, such
+// as the "_aws" timestamp, are held as Int64 values and other numbers as
+// Float64 values. The properties of the document, the root members that
+// are neither metric values nor dimension values, are also added as
+// attributes for filtering in the pipeline. The record timestamp is the
+// document timestamp.
+func FromDocument(doc []byte) (log.Record, error) {
+	var r log.Record
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var root map[string]interface{}
+	if err := dec.Decode(&root); err != nil {
+		return r, fmt.Errorf("otellog: decode document: %w", err)
+	}
+	var meta struct {
+		AWS struct {
+			Timestamp         int64 `json:"Timestamp"`
+			CloudWatchMetrics []struct {
+				Dimensions [][]string `json:"Dimensions"`
+				Metrics    []struct {
+					Name string `json:"Name"`
+				} `json:"Metrics"`
+			} `json:"CloudWatchMetrics"`
+		} `json:"_aws"`
+	}
+	if err := json.Unmarshal(doc, &meta); err != nil {
+		return r, fmt.Errorf("otellog: decode _aws member: %w", err)
+	}
+
+	r.SetTimestamp(time.Unix(0, meta.AWS.Timestamp*int64(time.Millisecond)))
+	r.SetBody(value(root))
+
+	reserved := map[string]bool{"_aws": true}
+	for _, directive := range meta.AWS.CloudWatchMetrics {
+		for _, set := range directive.Dimensions {
+			for _, key := range set {
+				reserved[key] = true
+			}
+		}
+		for _, mt := range directive.Metrics {
+			reserved[mt.Name] = true
+			// A metric nested by WithValuesNamespace lives under the
+			// first segment of its name.
+			if i := strings.IndexByte(mt.Name, '.'); i > 0 {
+				if _, ok := root[mt.Name[:i]].(map[string]interface{}); ok {
+					reserved[mt.Name[:i]] = true
+				}
+			}
+		}
+	}
+	for _, k := range sortedKeys(root) {
+		if !reserved[k] {
+			r.AddAttributes(log.KeyValue{Key: k, Value: value(root[k])})
+		}
+	}
+	return r, nil
+}
+
+// Sink is an emf.Sink emitting each document as a log record through an
+// OpenTelemetry logger.
+type Sink struct {
+	logger log.Logger
+}
+
+// NewSink returns a Sink emitting to logger.
+func NewSink(logger log.Logger) *Sink {
+	return &Sink{logger: logger}
+}
+
+// Emit converts doc with FromDocument and emits the record.
+func (s *Sink) Emit(doc []byte) error {
+	r, err := FromDocument(doc)
+	if err != nil {
+		return err
+	}
+	s.logger.Emit(context.Background(), r)
+	return nil
+}
+
+// value converts a JSON value decoded with UseNumber into a log value.
+func value(v interface{}) log.Value {
+	switch v := v.(type) {
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return log.Int64Value(n)
+		}
+		f, _ := v.Float64()
+		return log.Float64Value(f)
+	case []interface{}:
+		vs := make([]log.Value, len(v))
+		for i, e := range v {
+			vs[i] = value(e)
+		}
+		return log.SliceValue(vs...)
+	case map[string]interface{}:
+		kvs := make([]log.KeyValue, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			kvs = append(kvs, log.KeyValue{Key: k, Value: value(v[k])})
+		}
+		return log.MapValue(kvs...)
+	}
+	return log.Value{}
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// collector is an emf.Sink keeping the documents it is given.
+type collector [][]byte
+
+func (c *collector) Emit(doc []byte) error {
+	*c = append(*c, append([]byte(nil), doc...))
+	return nil
+}
@@ -0,0 +1,117 @@
+package otellog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// recordingLogger keeps the records it is given.
+type recordingLogger struct {
+	embedded.Logger
+	records []log.Record
+}
+
+func (l *recordingLogger) Emit(_ context.Context, r log.Record) {
+	l.records = append(l.records, r)
+}
+
+func (l *recordingLogger) Enabled(context.Context, log.EnabledParameters) bool {
+	return true
+}
+
+// member returns the value of key in the map value v.
+func member(v log.Value, key string) (log.Value, bool) {
+	for _, kv := range v.AsMap() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return log.Value{}, false
+}
+
+// attributes returns the attributes of r keyed by name.
+func attributes(r log.Record) map[string]log.Value {
+	attrs := make(map[string]log.Value)
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+func TestRecords(t *testing.T) {
+	ts := time.UnixMilli(1700000000123)
+	m := emf.NewMetric("NS")
+	m.SetTimestamp(ts)
+	m.AddDimension("Service", "api")
+	m.AddMetric("Latency", emf.Milliseconds, 12)
+	m.AddProperty("RequestId", "r-1")
+	m.AddProperty("Retries", 3)
+	m.AddProperty("Ratio", 0.5)
+
+	records, err := Records(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("converted %d records, want 1", len(records))
+	}
+	r := records[0]
+	if !r.Timestamp().Equal(ts) {
+		t.Errorf("timestamp = %v, want %v", r.Timestamp(), ts)
+	}
+
+	body := r.Body()
+	if body.Kind() != log.KindMap {
+		t.Fatalf("body kind = %v, want a map", body.Kind())
+	}
+	aws, ok := member(body, "_aws")
+	if !ok || aws.Kind() != log.KindMap {
+		t.Fatalf("body _aws = %v, want the metadata map", aws)
+	}
+	if got, ok := member(aws, "Timestamp"); !ok || got.Kind() != log.KindInt64 || got.AsInt64() != ts.UnixMilli() {
+		t.Errorf("_aws Timestamp = %v, want the Int64 %d", got, ts.UnixMilli())
+	}
+	if got, ok := member(body, "Service"); !ok || got.AsString() != "api" {
+		t.Errorf("body Service = %v, want api", got)
+	}
+
+	attrs := attributes(r)
+	for _, reserved := range []string{"_aws", "Service", "Latency"} {
+		if _, ok := attrs[reserved]; ok {
+			t.Errorf("%s is an attribute, want only properties", reserved)
+		}
+	}
+	if got := attrs["RequestId"]; got.Kind() != log.KindString || got.AsString() != "r-1" {
+		t.Errorf("RequestId attribute = %v, want the string r-1", got)
+	}
+	if got := attrs["Retries"]; got.Kind() != log.KindInt64 || got.AsInt64() != 3 {
+		t.Errorf("Retries attribute = %v, want the Int64 3", got)
+	}
+	if got := attrs["Ratio"]; got.Kind() != log.KindFloat64 || got.AsFloat64() != 0.5 {
+		t.Errorf("Ratio attribute = %v, want the Float64 0.5", got)
+	}
+}
+
+func TestSink(t *testing.T) {
+	logger := &recordingLogger{}
+	s := NewSink(logger)
+	if err := s.Emit([]byte(`{"_aws":{"Timestamp":1,"CloudWatchMetrics":[{"Namespace":"NS","Dimensions":[[]],"Metrics":[{"Name":"Requests"}]}]},"Requests":1,"Tenant":"t-1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.records) != 1 {
+		t.Fatalf("emitted %d records, want 1", len(logger.records))
+	}
+	if got := attributes(logger.records[0]); len(got) != 1 || got["Tenant"].AsString() != "t-1" {
+		t.Errorf("attributes = %v, want Tenant only", got)
+	}
+	if err := s.Emit([]byte("not json")); err == nil {
+		t.Error("Emit() of an invalid document succeeded")
+	}
+}
@@ -0,0 +1,60 @@
+package emf
+
+import "time"
+
+// Outcome dimension values and the property set by RecordOutcome.
+const (
+	OutcomeDimension = "Outcome"
+	OutcomeSuccess   = "Success"
+	OutcomeFailure   = "Failure"
+	ErrorProperty    = "Error"
+)
+
+// RecordOutcome counts one occurrence of operation, as a Count value of a
+// metric named after it recorded with AddMetricForDimensions under the
+// default dimension set plus an Outcome dimension of Success if err is nil
+// or Failure otherwise. The Outcome dimension applies to that value only,
+// so outcomes of several operations can be recorded in one document, and
+// the default dimension set is taken as it is when RecordOutcome is
+// called. On failure the error message is added as the Error property,
+// and if the document has a level (see WithLevel) it is raised to
+// LevelError.
+func (m *CloudWatchMetric) RecordOutcome(operation string, err error) {
+	m.AddMetricForDimensions(m.outcomeDimensions(err), operation, Count, 1)
+}
+
+// TimeOutcome starts timing a block and returns a function to call with the
+// block's error when it ends. The function records the elapsed time in
+// Milliseconds as the key metric, under the outcome dimensions that
+// RecordOutcome uses:
+//
+//	done := m.TimeOutcome("DBQuery")
+//	rows, err := db.Query(q)
+//	done(err)
+func (m *CloudWatchMetric) TimeOutcome(key string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+		m.AddMetricForDimensions(m.outcomeDimensions(err), key, Milliseconds, elapsed)
+	}
+}
+
+// outcomeDimensions returns the default dimension set plus the Outcome
+// dimension for err and, on failure, sets the Error and level properties.
+func (m *CloudWatchMetric) outcomeDimensions(err error) map[string]string {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeFailure
+		m.addError(err)
+	}
+	return m.defaultDimensionsWith(OutcomeDimension, outcome)
+}
+
+// addError sets the Error property to the message of err and, if the
+// document has a level, raises it to LevelError.
+func (m *CloudWatchMetric) addError(err error) {
+	m.AddProperty(ErrorProperty, err.Error())
+	if m.cfg.Level != "" {
+		m.AddProperty(LevelProperty, LevelError)
+	}
+}
@@ -0,0 +1,54 @@
+package emf
+
+import (
+	"errors"
+	"testing"
+)
+
+// metricDimension returns the value of the dimension key in the resolved
+// document holding the metric name, and whether such a document exists.
+func metricDimension(m *CloudWatchMetric, name, key string) (string, bool) {
+	for _, doc := range m.ResolveAll() {
+		if _, ok := doc.Root[name]; ok {
+			v, _ := doc.Root[key].(string)
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func TestRecordOutcomePerOperation(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Service", "api")
+	m.RecordOutcome("Get", nil)
+	m.RecordOutcome("Put", errors.New("conflict"))
+
+	for op, want := range map[string]string{"Get": OutcomeSuccess, "Put": OutcomeFailure} {
+		got, ok := metricDimension(&m, op, OutcomeDimension)
+		if !ok || got != want {
+			t.Errorf("%s Outcome = %q, want %q", op, got, want)
+		}
+		if svc, _ := metricDimension(&m, op, "Service"); svc != "api" {
+			t.Errorf("%s Service = %q, want api", op, svc)
+		}
+	}
+	if m.properties[ErrorProperty] != "conflict" {
+		t.Errorf("Error property = %v, want conflict", m.properties[ErrorProperty])
+	}
+	if _, ok := m.dimensionSets[0][OutcomeDimension]; ok {
+		t.Error("Outcome was added to the default dimension set")
+	}
+}
+
+func TestTimeOutcome(t *testing.T) {
+	m := NewMetric("NS")
+	m.TimeOutcome("Query")(nil)
+	m.TimeOutcome("Write")(errors.New("timeout"))
+
+	if got, _ := metricDimension(&m, "Query", OutcomeDimension); got != OutcomeSuccess {
+		t.Errorf("Query Outcome = %q, want Success", got)
+	}
+	if got, _ := metricDimension(&m, "Write", OutcomeDimension); got != OutcomeFailure {
+		t.Errorf("Write Outcome = %q, want Failure", got)
+	}
+}
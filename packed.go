@@ -0,0 +1,387 @@
+package emf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The BufferedEmitter queues documents in a compact binary form, packed
+// when they are emitted and converted to EMF JSON by the background writer.
+// A packed document holds the model MarshalJSON encodes, after emit-time
+// decoration, so that the JSON written is the same as that of an Emitter:
+//
+//	flags      byte: packedNumberFloat, packedLexicalOrder
+//	timestamp  varint, Unix milliseconds
+//	version    string
+//	valuesNS   string
+//	directives uvarint count, each: namespace string, dimension sets
+//	           (uvarint count, each a uvarint count of key strings) and
+//	           metrics (uvarint count, each name and unit strings)
+//	root       members
+//
+// Members are a uvarint count followed by, for each, a key string, a kind
+// byte and the value. Strings are written once, as a zero uvarint, the
+// uvarint length and the bytes; later occurrences are the uvarint of their
+// position in the order of first occurrence plus one, so that metric names
+// and dimension keys repeated between the "_aws" member and the root cost
+// a byte or two. Numbers are a tag byte followed by a varint for integral
+// values and by the IEEE 754 bits otherwise. Property values other than
+// strings are held as their JSON encoding.
+
+// Flags of a packed document.
+const (
+	packedNumberFloat = 1 << iota
+	packedLexicalOrder
+)
+
+// Kinds of packed member values.
+const (
+	packedString = iota
+	packedNumber
+	packedNumbers
+	packedWeighted
+	packedStatisticSet
+	packedObject
+	packedJSON
+)
+
+// Tags of packed numbers.
+const (
+	packedInt = iota
+	packedFloat
+)
+
+// maxPackedInt bounds the integral values packed as varints, beyond which
+// float64 no longer holds every integer.
+const maxPackedInt = 1 << 53
+
+// errCorruptPacked is returned when a packed document cannot be decoded.
+var errCorruptPacked = errors.New("emf: corrupt packed document")
+
+// pack returns the packed form of the document, failing as MarshalJSON
+// does.
+func (m *CloudWatchMetric) pack() ([]byte, error) {
+	if err := m.checkEncodable(); err != nil {
+		return nil, err
+	}
+	d := m.resolve(m.effectiveTimestamp())
+	var flags byte
+	if m.cfg.NumberStyle == NumberFloat {
+		flags |= packedNumberFloat
+	}
+	if m.cfg.MemberOrder == MemberOrderLexical {
+		flags |= packedLexicalOrder
+	}
+	p := packer{strings: make(map[string]uint64)}
+	p.buf = append(p.buf, flags)
+	p.buf = binary.AppendVarint(p.buf, d.Timestamp.UnixNano()/int64(time.Millisecond))
+	p.string(d.Version)
+	p.string(m.cfg.ValuesNamespace)
+	p.uvarint(len(d.Directives))
+	for _, dir := range d.Directives {
+		p.string(dir.Namespace)
+		p.uvarint(len(dir.Dimensions))
+		for _, keys := range dir.Dimensions {
+			p.uvarint(len(keys))
+			for _, k := range keys {
+				p.string(k)
+			}
+		}
+		p.uvarint(len(dir.Metrics))
+		for _, def := range dir.Metrics {
+			p.string(def.Name)
+			p.string(string(def.Unit))
+		}
+	}
+	if err := p.members(d.Root); err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.buf, nil
+}
+
+// unpack returns the newline-terminated EMF JSON of a packed document.
+func unpack(b []byte) ([]byte, error) {
+	u := unpacker{buf: b}
+	flags := u.byte()
+	ms := u.varint()
+	d := ResolvedDocument{Timestamp: time.Unix(0, ms*int64(time.Millisecond))}
+	d.Version = u.string()
+	ns := u.string()
+	d.Directives = make([]ResolvedDirective, u.count())
+	for i := range d.Directives {
+		dir := &d.Directives[i]
+		dir.Namespace = u.string()
+		dir.Dimensions = make([][]string, u.count())
+		for j := range dir.Dimensions {
+			dir.Dimensions[j] = make([]string, u.count())
+			for k := range dir.Dimensions[j] {
+				dir.Dimensions[j][k] = u.string()
+			}
+		}
+		dir.Metrics = make([]ResolvedMetric, u.count())
+		for j := range dir.Metrics {
+			dir.Metrics[j] = ResolvedMetric{Name: u.string(), Unit: Unit(u.string())}
+		}
+	}
+	d.Root = u.members()
+	if u.err != nil {
+		return nil, u.err
+	}
+	if len(u.buf) > 0 {
+		return nil, errCorruptPacked
+	}
+
+	if flags&packedNumberFloat != 0 {
+		d.forceFloats(ns)
+	}
+	order := MemberOrderEnvelopeFirst
+	if flags&packedLexicalOrder != 0 {
+		order = MemberOrderLexical
+	}
+	metrics := make(map[string]bool)
+	metricMembers(metrics, d.Directives, ns)
+	out, err := marshalRoot(orderRoot(d.object(), order, metrics))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// packer builds a packed document. err is set by the first number that
+// cannot be encoded.
+type packer struct {
+	buf     []byte
+	strings map[string]uint64
+	err     error
+}
+
+func (p *packer) uvarint(n int) {
+	p.buf = binary.AppendUvarint(p.buf, uint64(n))
+}
+
+// string appends s, or a reference to its earlier occurrence.
+func (p *packer) string(s string) {
+	if ref, ok := p.strings[s]; ok {
+		p.buf = binary.AppendUvarint(p.buf, ref)
+		return
+	}
+	p.strings[s] = uint64(len(p.strings)) + 1
+	p.buf = append(p.buf, 0)
+	p.uvarint(len(s))
+	p.buf = append(p.buf, s...)
+}
+
+// number appends v. Metric values are checked before packing, but NaN
+// and infinite property values are only found here; they are rejected as
+// encoding/json rejects them, so that Emit fails as an Emitter's does.
+func (p *packer) number(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		if p.err == nil {
+			p.err = &json.UnsupportedValueError{Value: reflect.ValueOf(v), Str: strconv.FormatFloat(v, 'g', -1, 64)}
+		}
+		return
+	}
+	if v == math.Trunc(v) && math.Abs(v) < maxPackedInt && !(v == 0 && math.Signbit(v)) {
+		p.buf = append(p.buf, packedInt)
+		p.buf = binary.AppendVarint(p.buf, int64(v))
+		return
+	}
+	p.buf = append(p.buf, packedFloat)
+	p.buf = binary.LittleEndian.AppendUint64(p.buf, math.Float64bits(v))
+}
+
+func (p *packer) numbers(values []float64) {
+	p.uvarint(len(values))
+	for _, v := range values {
+		p.number(v)
+	}
+}
+
+// members appends the members of root in lexical order of their keys.
+func (p *packer) members(root map[string]interface{}) error {
+	keys := make([]string, 0, len(root))
+	for k := range root {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	p.uvarint(len(keys))
+	for _, k := range keys {
+		p.string(k)
+		switch v := root[k].(type) {
+		case string:
+			p.buf = append(p.buf, packedString)
+			p.string(v)
+		case float64:
+			p.buf = append(p.buf, packedNumber)
+			p.number(v)
+		case []float64:
+			p.buf = append(p.buf, packedNumbers)
+			p.numbers(v)
+		case WeightedValues:
+			p.buf = append(p.buf, packedWeighted)
+			p.numbers(v.Values)
+			p.numbers(v.Counts)
+		case StatisticSet:
+			p.buf = append(p.buf, packedStatisticSet)
+			for _, n := range []float64{v.Min, v.Max, v.Sum, v.SampleCount} {
+				p.number(n)
+			}
+		case map[string]interface{}:
+			p.buf = append(p.buf, packedObject)
+			if err := p.members(v); err != nil {
+				return err
+			}
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			p.buf = append(p.buf, packedJSON)
+			p.uvarint(len(b))
+			p.buf = append(p.buf, b...)
+		}
+	}
+	return nil
+}
+
+// unpacker decodes a packed document. Once a read fails, err is set and
+// every further read returns a zero value.
+type unpacker struct {
+	buf     []byte
+	strings []string
+	err     error
+}
+
+func (u *unpacker) fail() {
+	if u.err == nil {
+		u.err = errCorruptPacked
+	}
+	u.buf = nil
+}
+
+func (u *unpacker) byte() byte {
+	if len(u.buf) == 0 {
+		u.fail()
+		return 0
+	}
+	b := u.buf[0]
+	u.buf = u.buf[1:]
+	return b
+}
+
+func (u *unpacker) uvarint() uint64 {
+	n, size := binary.Uvarint(u.buf)
+	if size <= 0 {
+		u.fail()
+		return 0
+	}
+	u.buf = u.buf[size:]
+	return n
+}
+
+func (u *unpacker) varint() int64 {
+	n, size := binary.Varint(u.buf)
+	if size <= 0 {
+		u.fail()
+		return 0
+	}
+	u.buf = u.buf[size:]
+	return n
+}
+
+// count returns a count of items, each taking at least a byte, so that a
+// corrupt count cannot make the caller allocate more than the input holds.
+func (u *unpacker) count() int {
+	n := u.uvarint()
+	if n > uint64(len(u.buf)) {
+		u.fail()
+		return 0
+	}
+	return int(n)
+}
+
+// bytes returns the next n bytes.
+func (u *unpacker) bytes(n int) []byte {
+	if n > len(u.buf) {
+		u.fail()
+		return nil
+	}
+	b := u.buf[:n:n]
+	u.buf = u.buf[n:]
+	return b
+}
+
+func (u *unpacker) string() string {
+	ref := u.uvarint()
+	if ref > 0 {
+		if ref > uint64(len(u.strings)) {
+			u.fail()
+			return ""
+		}
+		return u.strings[ref-1]
+	}
+	s := string(u.bytes(u.count()))
+	if u.err == nil {
+		u.strings = append(u.strings, s)
+	}
+	return s
+}
+
+func (u *unpacker) number() float64 {
+	switch u.byte() {
+	case packedInt:
+		return float64(u.varint())
+	case packedFloat:
+		b := u.bytes(8)
+		if b == nil {
+			return 0
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	u.fail()
+	return 0
+}
+
+func (u *unpacker) numbers() []float64 {
+	values := make([]float64, u.count())
+	for i := range values {
+		values[i] = u.number()
+	}
+	return values
+}
+
+func (u *unpacker) members() map[string]interface{} {
+	n := u.count()
+	root := make(map[string]interface{}, n)
+	for i := 0; i < n && u.err == nil; i++ {
+		k := u.string()
+		switch u.byte() {
+		case packedString:
+			root[k] = u.string()
+		case packedNumber:
+			root[k] = u.number()
+		case packedNumbers:
+			root[k] = u.numbers()
+		case packedWeighted:
+			root[k] = WeightedValues{Values: u.numbers(), Counts: u.numbers()}
+		case packedStatisticSet:
+			root[k] = StatisticSet{Min: u.number(), Max: u.number(), Sum: u.number(), SampleCount: u.number()}
+		case packedObject:
+			root[k] = u.members()
+		case packedJSON:
+			root[k] = json.RawMessage(u.bytes(u.count()))
+		default:
+			u.fail()
+		}
+	}
+	return root
+}
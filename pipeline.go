@@ -0,0 +1,74 @@
+package emf
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultFlushInterval is the interval a Pipeline flushes at when
+// NewPipeline is given one that is not positive.
+const DefaultFlushInterval = time.Minute
+
+// Pipeline aggregates events received on a channel into a Collector and
+// periodically flushes it to a Sink from a single goroutine.
+type Pipeline struct {
+	events    chan Event
+	collector *Collector
+	sink      Sink
+	interval  time.Duration
+}
+
+// NewPipeline returns a Pipeline aggregating into collector and flushing to
+// sink every interval, or every DefaultFlushInterval if interval is not
+// positive. The input channel buffers up to buffer events.
+func NewPipeline(collector *Collector, sink Sink, interval time.Duration, buffer int) *Pipeline {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	return &Pipeline{
+		events:    make(chan Event, buffer),
+		collector: collector,
+		sink:      sink,
+		interval:  interval,
+	}
+}
+
+// Events returns the channel events are sent to. Sends block while the
+// buffer is full and Run is not receiving.
+func (p *Pipeline) Events() chan<- Event {
+	return p.events
+}
+
+// Run receives events and flushes the collector every interval until ctx
+// is done, then records the events still buffered, flushes a final time
+// and returns. Producers should stop sending once ctx is done, as nothing
+// receives afterwards. Run returns the first flush error, if any.
+func (p *Pipeline) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	var firstErr error
+	flush := func() {
+		if err := p.collector.Flush(p.sink); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for {
+		select {
+		case e := <-p.events:
+			p.collector.Record(e)
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case e := <-p.events:
+					p.collector.Record(e)
+				default:
+					flush()
+					return firstErr
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package emf
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// errSink fails every Emit with err.
+type errSink struct{ err error }
+
+func (s errSink) Emit([]byte) error { return s.err }
+
+func TestPipelineFlushesEveryInterval(t *testing.T) {
+	sink := &bufferSink{}
+	p := NewPipeline(NewCollector("NS"), sink, time.Millisecond, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	p.Events() <- Event{Name: "Requests", Unit: Count, Value: 1}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(decodeDocs(t, sink)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no document flushed before cancellation")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if docs := decodeDocs(t, sink); docs[0]["Requests"] != 1.0 {
+		t.Errorf("flushed %v, want Requests 1", docs[0])
+	}
+}
+
+func TestPipelineDrainsOnCancel(t *testing.T) {
+	sink := &bufferSink{}
+	p := NewPipeline(NewCollector("NS"), sink, time.Hour, 3)
+	for _, host := range []string{"a", "b", "a"} {
+		p.Events() <- Event{Dimensions: map[string]string{"Host": host}, Name: "Requests", Unit: Count, Value: 1}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeDocs(t, sink)
+	if len(docs) != 2 || docs[0]["Host"] != "a" || docs[1]["Host"] != "b" {
+		t.Fatalf("flushed %v, want the documents of hosts a and b", docs)
+	}
+	if got, ok := docs[0]["Requests"].([]interface{}); !ok || len(got) != 2 {
+		t.Errorf("host a Requests = %v, want both buffered values", docs[0]["Requests"])
+	}
+}
+
+func TestPipelineReturnsFlushError(t *testing.T) {
+	want := errors.New("sink down")
+	p := NewPipeline(NewCollector("NS"), errSink{want}, time.Hour, 1)
+	p.Events() <- Event{Name: "Requests", Unit: Count, Value: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Run(ctx); !errors.Is(err, want) {
+		t.Errorf("Run() = %v, want %v", err, want)
+	}
+}
+
+func TestNewPipelineDefaultsInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if p := NewPipeline(NewCollector("NS"), &bufferSink{}, interval, 0); p.interval != DefaultFlushInterval {
+			t.Errorf("NewPipeline(%v) interval = %v, want %v", interval, p.interval, DefaultFlushInterval)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package emf
+
+import "sort"
+
+// SchemaVersionProperty is the property that WithSchemaVersion sets.
+const SchemaVersionProperty = "_schemaVersion"
+
+// WithSchemaVersion adds a _schemaVersion property holding v to the
+// document, so that downstream processors can tell versions of the
+// document shape apart and handle migrations. It describes the caller's
+// own schema and is unrelated to the EMF specification version. It is not
+// counted by WithMaxProperties. By default no schema version is emitted.
+func WithSchemaVersion(v string) Option {
+	return func(c *Config) {
+		c.SchemaVersion = v
+	}
+}
+
+// WithMaxProperties caps the number of properties a document is emitted
+// with at n, guarding event size against high-cardinality properties.
+// When there are more, the least recently set properties are left out and
+// onDrop, if not nil, is called with their names, least recent first, each
+// time the document is marshalled. The stored properties are not changed.
+// By default the number of properties is unlimited.
+func WithMaxProperties(n int, onDrop func(dropped []string)) Option {
+	return func(c *Config) {
+		c.MaxProperties = n
+		c.OnDroppedProperties = onDrop
+	}
+}
+
+// emittedProperties returns the properties the document is emitted with,
+// applying the MaxProperties cap.
+func (m *CloudWatchMetric) emittedProperties() map[string]interface{} {
+	max := m.cfg.MaxProperties
+	if max <= 0 || len(m.properties) <= max {
+		return m.properties
+	}
+	keys := make([]string, 0, len(m.properties))
+	for k := range m.properties {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return m.propertySeq[keys[i]] < m.propertySeq[keys[j]]
+	})
+	dropped := keys[:len(keys)-max]
+	kept := make(map[string]interface{}, max)
+	for _, k := range keys[len(dropped):] {
+		kept[k] = m.properties[k]
+	}
+	if m.cfg.OnDroppedProperties != nil {
+		m.cfg.OnDroppedProperties(dropped)
+	}
+	return kept
+}
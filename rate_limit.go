@@ -0,0 +1,79 @@
+package emf
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitMode is what an emitter does with documents over its rate
+// limit.
+type RateLimitMode int
+
+const (
+	// RateLimitDrop discards documents over the limit, counting them in
+	// Stats.RateLimited.
+	RateLimitDrop RateLimitMode = iota
+	// RateLimitBlock delays documents over the limit until they fit.
+	RateLimitBlock
+)
+
+// WithRateLimit caps the documents the emitter hands to its sink at
+// perSecond per second, allowing bursts of up to perSecond documents, so
+// that a bug emitting in a loop cannot flood CloudWatch Logs. Documents
+// over the limit are dropped or delayed according to mode; a
+// BufferedEmitter delays them in its background goroutine, so that its
+// buffer fills and Emit eventually blocks. A perSecond of zero or less
+// means no limit, the default.
+func WithRateLimit(perSecond int, mode RateLimitMode) EmitterOption {
+	return func(e *Emitter) {
+		if perSecond <= 0 {
+			e.limiter = nil
+			return
+		}
+		e.limiter = &rateLimiter{
+			rate:   float64(perSecond),
+			tokens: float64(perSecond),
+			block:  mode == RateLimitBlock,
+		}
+	}
+}
+
+// rateLimiter is a token bucket holding up to rate tokens, refilled at
+// rate tokens per second.
+type rateLimiter struct {
+	rate  float64
+	block bool
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token, waiting for one under RateLimitBlock, and reports
+// whether the document may be written.
+func (l *rateLimiter) take() bool {
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return true
+	}
+	if !l.block {
+		l.mu.Unlock()
+		return false
+	}
+	// Reserve the next token; later callers queue behind it.
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.tokens--
+	l.mu.Unlock()
+	time.Sleep(wait)
+	return true
+}
@@ -0,0 +1,28 @@
+package emf
+
+// WithSkipUndefinedRatios makes AddRatio record nothing when the
+// denominator is zero, so that the metric shows missing data. By default
+// such a ratio is recorded as 0.
+func WithSkipUndefinedRatios() Option {
+	return func(c *Config) {
+		c.SkipUndefinedRatios = true
+	}
+}
+
+// AddRatio records numerator/denominator, for example an error rate from
+// error and request counts. With the Percent unit the ratio is multiplied
+// by 100. A zero denominator records 0, or nothing under
+// WithSkipUndefinedRatios.
+func (m *CloudWatchMetric) AddRatio(key string, numerator, denominator float64, unit Unit) {
+	if denominator == 0 {
+		if !m.cfg.SkipUndefinedRatios {
+			m.AddMetric(key, unit, 0)
+		}
+		return
+	}
+	ratio := numerator / denominator
+	if unit == Percent {
+		ratio *= 100
+	}
+	m.AddMetric(key, unit, ratio)
+}
@@ -0,0 +1,55 @@
+package emf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// EmitRaw hands a document serialized elsewhere to the sink, so that EMF
+// from other sources can share the emitter. data must be a single JSON
+// value on one line terminated by a newline; it is otherwise rejected
+// without being emitted. The emitter's document options do not apply.
+func (e *Emitter) EmitRaw(data []byte) error {
+	if err := e.checkRaw(data); err != nil {
+		return err
+	}
+	return e.write(data)
+}
+
+// EmitRaw queues a document serialized elsewhere for writing, as
+// Emitter.EmitRaw does. data is copied, so the caller may reuse it.
+func (b *BufferedEmitter) EmitRaw(data []byte) error {
+	if err := b.emitter.checkRaw(data); err != nil {
+		return err
+	}
+	return b.enqueue(append([]byte(nil), data...))
+}
+
+// checkRaw validates a document passed to EmitRaw, recording a failure in
+// the emitter's stats.
+func (e *Emitter) checkRaw(data []byte) error {
+	err := validateRaw(data)
+	if err != nil {
+		e.failed(err)
+	}
+	return err
+}
+
+// validateRaw checks that data is one newline-terminated line of JSON.
+func validateRaw(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("emf: raw document is empty")
+	}
+	if data[len(data)-1] != '\n' {
+		return errors.New("emf: raw document does not end in a newline")
+	}
+	line := data[:len(data)-1]
+	if bytes.IndexByte(line, '\n') >= 0 {
+		return errors.New("emf: raw document spans several lines")
+	}
+	if !json.Valid(line) {
+		return errors.New("emf: raw document is not valid JSON")
+	}
+	return nil
+}
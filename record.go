@@ -0,0 +1,87 @@
+package emf
+
+import (
+	"fmt"
+	"time"
+)
+
+// Record adds value to the named metric, inferring the unit from its type:
+//
+//   - time.Duration is recorded in Milliseconds. If unit is Seconds or
+//     Microseconds the duration is converted to that unit instead.
+//   - Signed and unsigned integers and floats are recorded as Count unless a
+//     unit is given.
+//
+// At most one unit may be passed. Any other value type is an error and
+// nothing is recorded.
+func (m *CloudWatchMetric) Record(key string, value interface{}, unit ...Unit) error {
+	if len(unit) > 1 {
+		return fmt.Errorf("emf: Record %q: at most one unit may be given", key)
+	}
+	var u Unit
+	if len(unit) == 1 {
+		u = unit[0]
+	}
+
+	if d, ok := value.(time.Duration); ok {
+		var scale time.Duration
+		switch u {
+		case "", Milliseconds:
+			u, scale = Milliseconds, time.Millisecond
+		case Seconds:
+			scale = time.Second
+		case Microseconds:
+			scale = time.Microsecond
+		default:
+			return fmt.Errorf("emf: Record %q: cannot record a duration as %s", key, u)
+		}
+		m.AddMetric(key, u, float64(d)/float64(scale))
+		return nil
+	}
+
+	var f float64
+	switch v := value.(type) {
+	case int:
+		f = float64(v)
+	case int8:
+		f = float64(v)
+	case int16:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint:
+		f = float64(v)
+	case uint8:
+		f = float64(v)
+	case uint16:
+		f = float64(v)
+	case uint32:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		return fmt.Errorf("emf: Record %q: unsupported value type %T", key, value)
+	}
+	if u == "" {
+		u = Count
+	}
+	m.AddMetric(key, u, f)
+	return nil
+}
+
+// AddLatencyFrom records the time elapsed since start, in Milliseconds.
+func (m *CloudWatchMetric) AddLatencyFrom(key string, start time.Time) {
+	m.AddLatencyBetween(key, start, time.Now())
+}
+
+// AddLatencyBetween records the time from start to end, in Milliseconds.
+// The value is negative if end is before start.
+func (m *CloudWatchMetric) AddLatencyBetween(key string, start, end time.Time) {
+	m.AddMetric(key, Milliseconds, float64(end.Sub(start))/float64(time.Millisecond))
+}
@@ -0,0 +1,25 @@
+package emf
+
+// RecordOnce appends value to the named metric as AddMetric does, unless a
+// value was already recorded for the metric with the same dedupeKey in the
+// current window, so that an event delivered twice, by a retry or an
+// at-least-once queue, is counted once. The keys are forgotten when the
+// metric's values are cleared, by Reset, or by EndWindow for windowed
+// metrics. It reports whether the value was recorded.
+func (m *CloudWatchMetric) RecordOnce(key string, unit Unit, value float64, dedupeKey string) bool {
+	m.lazyInit()
+	mt, ok := m.metrics[key]
+	if !ok {
+		mt = &metric{unit: unit}
+		m.metrics[key] = mt
+	}
+	if mt.seen[dedupeKey] {
+		return false
+	}
+	if mt.seen == nil {
+		mt.seen = make(map[string]bool)
+	}
+	mt.seen[dedupeKey] = true
+	m.AddMetric(key, unit, value)
+	return true
+}
@@ -0,0 +1,70 @@
+package emf
+
+import "regexp"
+
+// Redactor returns the value to emit for a dimension or property value,
+// for example with personal data masked.
+type Redactor func(key, value string) string
+
+// RedactedValue replaces what RegexpRedactor redacts.
+const RedactedValue = "[REDACTED]"
+
+// CommonRedactionPatterns match values commonly regarded as sensitive:
+// email addresses, bearer tokens, JSON web tokens and AWS access key IDs.
+var CommonRedactionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/-]+=*`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+}
+
+// RegexpRedactor returns a Redactor replacing every match of patterns with
+// RedactedValue, whatever the key. Without patterns it uses
+// CommonRedactionPatterns.
+func RegexpRedactor(patterns ...*regexp.Regexp) Redactor {
+	if len(patterns) == 0 {
+		patterns = CommonRedactionPatterns
+	}
+	return func(_, value string) string {
+		for _, p := range patterns {
+			value = p.ReplaceAllLiteralString(value, RedactedValue)
+		}
+		return value
+	}
+}
+
+// WithRedactor makes the emitter pass every dimension value and string
+// property value of the documents it emits through r, including those the
+// emitter adds itself, so that redaction cannot be forgotten at a call
+// site. Values are redacted in copies; the documents given to Emit are
+// left untouched. Redactors given in several options are applied in
+// order. Documents given to EmitRaw are not redacted.
+func WithRedactor(r Redactor) EmitterOption {
+	return func(e *Emitter) {
+		e.redactors = append(e.redactors, r)
+	}
+}
+
+// redact passes the dimension and string property values of m through
+// the redactors.
+func (m *CloudWatchMetric) redact(redactors []Redactor) {
+	apply := func(key, value string) string {
+		for _, r := range redactors {
+			value = r(key, value)
+		}
+		return value
+	}
+	for _, set := range m.dimensionSets {
+		for k, v := range set {
+			set[k] = apply(k, v)
+		}
+	}
+	for k, v := range m.primarySet {
+		m.primarySet[k] = apply(k, v)
+	}
+	for k, v := range m.properties {
+		if s, ok := v.(string); ok {
+			m.properties[k] = apply(k, s)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package emf
+
+// Reducer reduces the values of a metric to the single value emitted for
+// it. It is only called with two or more values.
+type Reducer func(values []float64) float64
+
+// Sum is a Reducer emitting the sum of the values.
+func Sum(values []float64) float64 {
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+// Mean is a Reducer emitting the arithmetic mean of the values.
+func Mean(values []float64) float64 {
+	return Sum(values) / float64(len(values))
+}
+
+// Max is a Reducer emitting the largest value.
+func Max(values []float64) float64 {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+// Min is a Reducer emitting the smallest value.
+func Min(values []float64) float64 {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// Last is a Reducer emitting the most recently recorded value.
+func Last(values []float64) float64 {
+	return values[len(values)-1]
+}
+
+// WithReducer makes the named metric emit the single value reducer
+// computes from its recorded values when the document is marshalled,
+// instead of every value. This shrinks documents, at the cost of the
+// percentiles CloudWatch would compute from the individual values. The
+// recorded values are not changed, and values beyond the per-metric limit
+// are included in the reduction. Histograms and statistic sets are emitted
+// unreduced. By default every value is emitted.
+func WithReducer(key string, reducer Reducer) Option {
+	return func(c *Config) {
+		if c.Reducers == nil {
+			c.Reducers = make(map[string]Reducer)
+		}
+		c.Reducers[key] = reducer
+	}
+}
+
+// reduces reports whether the values of the named metric are reduced to
+// one when the document is marshalled.
+func (m *CloudWatchMetric) reduces(name string) bool {
+	_, ok := m.cfg.Reducers[name]
+	return ok && m.metrics[name].counts == nil
+}
@@ -0,0 +1,53 @@
+package emf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReducers(t *testing.T) {
+	values := []float64{4, 1, 7, 2}
+	for name, tt := range map[string]struct {
+		reduce Reducer
+		want   float64
+	}{
+		"Sum":  {Sum, 14},
+		"Mean": {Mean, 3.5},
+		"Max":  {Max, 7},
+		"Min":  {Min, 1},
+		"Last": {Last, 2},
+	} {
+		if got := tt.reduce(values); got != tt.want {
+			t.Errorf("%s(%v) = %v, want %v", name, values, got, tt.want)
+		}
+	}
+}
+
+func TestFlushChunkedReducesBeforeChunking(t *testing.T) {
+	m := NewMetric("NS", WithReducer("Mean", Mean), WithReducer("Last", Last))
+	for i := 0; i < 250; i++ {
+		m.AddMetric("Latency", Milliseconds, float64(i))
+		m.AddMetric("Mean", Milliseconds, float64(i))
+		m.AddMetric("Last", Milliseconds, float64(i))
+	}
+
+	var buf bytes.Buffer
+	if err := m.FlushChunked(&buf); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeLines(t, &buf)
+	if len(docs) != 3 {
+		t.Fatalf("wrote %d documents, want 3", len(docs))
+	}
+	if docs[0]["Mean"] != 124.5 || docs[0]["Last"] != 249.0 {
+		t.Errorf("first document Mean = %v, Last = %v, want 124.5 and 249 over every value", docs[0]["Mean"], docs[0]["Last"])
+	}
+	for i, doc := range docs[1:] {
+		if _, ok := doc["Mean"]; ok {
+			t.Errorf("document %d holds Mean again", i+1)
+		}
+		if _, ok := doc["Last"]; ok {
+			t.Errorf("document %d holds Last again", i+1)
+		}
+	}
+}
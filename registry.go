@@ -0,0 +1,68 @@
+package emf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry declares metrics once so that code records them through typed
+// handles rather than repeating names and units. A misspelt handle is a
+// compile error where a misspelt name would silently create a new metric.
+//
+//	var (
+//		metrics = emf.NewRegistry()
+//		Latency = metrics.Define("Latency", emf.Milliseconds)
+//	)
+//
+//	m.RecordHandle(Latency, 12.5)
+type Registry struct {
+	mu    sync.Mutex
+	units map[string]Unit
+}
+
+// MetricHandle refers to a metric declared with Registry.Define.
+type MetricHandle struct {
+	name string
+	unit Unit
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{units: make(map[string]Unit)}
+}
+
+// Define declares a metric and returns its handle. Defining the same name
+// again with the same unit returns an equal handle. Define panics if name
+// is empty, unit is invalid or name was already defined with another unit,
+// since these are programming errors usually made in package-level
+// declarations.
+func (r *Registry) Define(name string, unit Unit) MetricHandle {
+	if name == "" {
+		panic("emf: Define: metric name is empty")
+	}
+	if !unit.Valid() {
+		panic(fmt.Sprintf("emf: Define %q: invalid unit %q", name, unit))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.units[name]; ok && u != unit {
+		panic(fmt.Sprintf("emf: Define %q: already defined with unit %s", name, u))
+	}
+	r.units[name] = unit
+	return MetricHandle{name: name, unit: unit}
+}
+
+// Name returns the metric name of the handle.
+func (h MetricHandle) Name() string {
+	return h.name
+}
+
+// Unit returns the unit the metric was defined with.
+func (h MetricHandle) Unit() Unit {
+	return h.unit
+}
+
+// RecordHandle adds value to the metric h refers to, in its defined unit.
+func (m *CloudWatchMetric) RecordHandle(h MetricHandle, value float64) {
+	m.AddMetric(h.name, h.unit, value)
+}
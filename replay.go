@@ -0,0 +1,68 @@
+package emf
+
+import (
+	"bufio"
+	"os"
+)
+
+// RecordingSink passes documents on to another Sink and also appends them
+// to a file, in the newline-delimited form ReplayFile reads. It is created
+// by RecordToFile.
+type RecordingSink struct {
+	sink Sink
+	file *FileSink
+}
+
+// RecordToFile returns a Sink that emits documents to sink and records them
+// in the file at path, which is created if needed and appended to. A
+// document is recorded even if sink fails to emit it. Close the returned
+// sink to close the file.
+func RecordToFile(sink Sink, path string) (*RecordingSink, error) {
+	f, err := NewFileSink(path, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordingSink{sink: sink, file: f}, nil
+}
+
+// Emit records doc and emits it to the wrapped sink, returning the first
+// error of either.
+func (s *RecordingSink) Emit(doc []byte) error {
+	ferr := s.file.Emit(doc)
+	if err := s.sink.Emit(doc); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// Close closes the recording file. The wrapped sink is not closed.
+func (s *RecordingSink) Close() error {
+	return s.file.Close()
+}
+
+// ReplayFile emits every document recorded in the file at path to sink, in
+// order, to reproduce a metric sequence locally or for load testing. Empty
+// lines are skipped. It stops at the first error.
+func ReplayFile(path string, sink Sink) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 4*MaxEventBytes)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		doc := make([]byte, len(line)+1)
+		copy(doc, line)
+		doc[len(line)] = '\n'
+		if err := sink.Emit(doc); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
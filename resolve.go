@@ -0,0 +1,103 @@
+package emf
+
+import "time"
+
+// ResolvedDocument is the model a CloudWatchMetric is encoded from, after
+// truncation to CloudWatch limits, dimension deduplication and the document
+// options have been applied. It lets tests assert on the document structure
+// without parsing JSON.
+type ResolvedDocument struct {
+	// Timestamp is the timestamp that is emitted.
+	Timestamp time.Time
+	// Version is the EMF specification version emitted in the "_aws"
+	// member.
+	Version string
+	// Directives are the metric directives of the "_aws" member.
+	Directives []ResolvedDirective
+	// Root holds every root member other than "_aws": properties,
+	// dimension values and metric values. A metric with a single value is
+	// held as a float64, a metric with statistic sets as a StatisticSet, a
+	// metric with value counts as WeightedValues, otherwise values are held
+	// as a []float64.
+	Root map[string]interface{}
+}
+
+// ResolvedDirective tells CloudWatch which root members to extract as
+// metrics.
+type ResolvedDirective struct {
+	Namespace  string           `json:"Namespace"`
+	Dimensions [][]string       `json:"Dimensions"`
+	Metrics    []ResolvedMetric `json:"Metrics"`
+}
+
+// ResolvedMetric names a root member holding metric values.
+type ResolvedMetric struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit,omitempty"`
+}
+
+// envelope is the "_aws" member of an EMF document.
+type envelope struct {
+	Timestamp         int64               `json:"Timestamp"`
+	CloudWatchMetrics []ResolvedDirective `json:"CloudWatchMetrics"`
+	Version           string              `json:"Version"`
+}
+
+// Resolve returns the model the document would be encoded from if it were
+// marshalled now. Truncation is applied regardless of the limit policy.
+// Like MarshalJSON it describes a single document; see ResolveAll.
+func (m *CloudWatchMetric) Resolve() ResolvedDocument {
+	return m.resolve(m.effectiveTimestamp())
+}
+
+// ResolveAll returns the models of every document m is written as, in the
+// order Write writes them: the document itself, the documents of
+// statistic sets with timestamps of their own and the documents of the
+// scoped metrics recorded with AddMetricForDimensions or Merge, which
+// Resolve leaves out.
+func (m *CloudWatchMetric) ResolveAll() []ResolvedDocument {
+	docs := m.split()
+	resolved := make([]ResolvedDocument, 0, len(docs))
+	for _, doc := range docs {
+		resolved = append(resolved, doc.Resolve())
+	}
+	return resolved
+}
+
+// resolve builds the document model for the timestamp ts.
+func (m *CloudWatchMetric) resolve(ts time.Time) ResolvedDocument {
+	m = m.withDerivedDimensions(ts)
+	root := make(map[string]interface{}, len(m.properties)+len(m.metrics)+1)
+	if m.cfg.Level != "" {
+		root[LevelProperty] = m.cfg.Level
+	}
+	if !m.cfg.ContextDimensions {
+		for k, v := range m.cfg.contextMembers() {
+			root[k] = v
+		}
+	}
+	for k, v := range m.emittedProperties() {
+		root[k] = v
+	}
+	if m.cfg.SchemaVersion != "" {
+		root[SchemaVersionProperty] = m.cfg.SchemaVersion
+	}
+	directive := m.fill(root, ts)
+	return ResolvedDocument{
+		Timestamp:  ts,
+		Version:    m.emfVersion(),
+		Directives: []ResolvedDirective{directive},
+		Root:       root,
+	}
+}
+
+// object returns the JSON object for the document. It adds the "_aws"
+// member to d.Root and returns it.
+func (d ResolvedDocument) object() map[string]interface{} {
+	d.Root["_aws"] = envelope{
+		Timestamp:         d.Timestamp.UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: d.Directives,
+		Version:           d.Version,
+	}
+	return d.Root
+}
@@ -0,0 +1,20 @@
+package emf
+
+import "testing"
+
+func TestResolveAllIncludesScopedDocuments(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, 5)
+	m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Requests", Count, 1)
+
+	docs := m.ResolveAll()
+	if len(docs) != 2 {
+		t.Fatalf("ResolveAll() returned %d documents, want 2", len(docs))
+	}
+	if _, ok := docs[0].Root["Latency"]; !ok {
+		t.Errorf("first document = %v, want the Latency metric", docs[0].Root)
+	}
+	if docs[1].Root["Requests"] != 1.0 || docs[1].Root["Operation"] != "Get" {
+		t.Errorf("second document = %v, want Requests under Operation=Get", docs[1].Root)
+	}
+}
@@ -0,0 +1,31 @@
+package emf
+
+import (
+	"context"
+	"time"
+)
+
+// SampleGauge calls fn every interval and emits its result to sink as the
+// key gauge of a document in namespace, stamped with the time the sample
+// is taken. It suits resource gauges such as goroutine counts or heap
+// size. It blocks, so run it on its own goroutine; it returns promptly
+// once ctx is done. Sink errors are ignored, as with Heartbeat.
+func SampleGauge(ctx context.Context, namespace, key string, unit Unit, interval time.Duration, fn func() float64, sink Sink) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Both cases may be ready at once; do not sample after
+			// cancellation.
+			if ctx.Err() != nil {
+				return
+			}
+			m := NewMetric(namespace)
+			m.SetGauge(key, unit, fn())
+			_ = m.EmitTo(sink)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package emf
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSampleGaugeStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &bufferSink{}
+	samples := 0
+	done := make(chan struct{})
+	go func() {
+		SampleGauge(ctx, "NS", "Goroutines", Count, time.Millisecond, func() float64 {
+			samples++
+			if samples == 3 {
+				cancel()
+			}
+			return float64(samples)
+		}, sink)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SampleGauge did not return after ctx was cancelled")
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.docs) != 3 {
+		t.Fatalf("emitted %d samples, want 3", len(sink.docs))
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(sink.docs[2], &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["Goroutines"] != 3.0 {
+		t.Errorf("last sample = %v, want 3", doc["Goroutines"])
+	}
+}
+
+func TestSampleGaugeCancelledBeforeFirstTick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sink := &bufferSink{}
+	SampleGauge(ctx, "NS", "Goroutines", Count, time.Hour, func() float64 { return 1 }, sink)
+	if len(sink.docs) != 0 {
+		t.Errorf("emitted %d samples, want none", len(sink.docs))
+	}
+}
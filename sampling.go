@@ -0,0 +1,92 @@
+package emf
+
+import "math/rand"
+
+// SampledCountSuffix is appended to the name of a sampled metric to name
+// the metric counting all of its values. See WithValueSampling.
+const SampledCountSuffix = ".count"
+
+// WithValueSampling makes AddMetric store only a random fraction rate of
+// the values recorded for the named metric, bounding the memory and
+// document size of high-volume metrics. Every value is still counted: the
+// metric key+SampledCountSuffix holds the exact number of values recorded,
+// as a Count metric, while the key metric holds the sampled values, so
+// its percentiles are approximate and its sample count is not the total.
+// A rate of 1 or more stores every value and a rate of 0 or less none. By
+// default every value is stored.
+func WithValueSampling(key string, rate float64) Option {
+	return func(c *Config) {
+		if c.SampleRates == nil {
+			c.SampleRates = make(map[string]float64)
+		}
+		c.SampleRates[key] = rate
+	}
+}
+
+// sampleValue counts a value recorded for the named metric if it is
+// sampled, and reports whether the value should be stored.
+func (m *CloudWatchMetric) sampleValue(key string) bool {
+	rate, ok := m.cfg.SampleRates[key]
+	if !ok {
+		return true
+	}
+	m.Add(key+SampledCountSuffix, 1)
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// SampleRateSuffix is appended to the name of a metric recorded with
+// AddSampled to name the property holding its sample rate.
+const SampleRateSuffix = ".sampleRate"
+
+// WithSampleScaling makes AddSampled scale sampled values on the client
+// instead of leaving it to consumers. See AddSampled.
+func WithSampleScaling() Option {
+	return func(c *Config) {
+		c.ScaleSampled = true
+	}
+}
+
+// AddSampled records value for the named metric as one observation out of
+// a sample taken at sampleRate, with StatsD semantics: a rate of 0.1 means
+// the caller records about one event in ten. A rate outside (0, 1) means
+// the value was not sampled and it is recorded as AddMetric does.
+//
+// By default scaling is left to the consumer: the value is recorded as is
+// and the rate is set as the property key+SampleRateSuffix, holding the
+// rate of the last call. Under WithSampleScaling the value is scaled on the
+// client instead, recorded with a count of 1/sampleRate so that the metric
+// is emitted with Values and Counts arrays and CloudWatch's sample count,
+// sum and percentiles account for the unsampled events, as a StatsD server
+// scales sampled counters and timers. Scaling also handles calls with
+// different rates for the same metric, which a single property cannot.
+func (m *CloudWatchMetric) AddSampled(key string, unit Unit, value, sampleRate float64) {
+	if sampleRate <= 0 || sampleRate >= 1 {
+		m.AddMetric(key, unit, value)
+		return
+	}
+	if !m.cfg.ScaleSampled {
+		m.AddMetric(key, unit, value)
+		m.AddProperty(key+SampleRateSuffix, sampleRate)
+		return
+	}
+	m.lazyInit()
+	mt, ok := m.metrics[key]
+	if !ok {
+		mt = &metric{unit: unit}
+		m.metrics[key] = mt
+	}
+	mt.addWeighted(value, 1/sampleRate)
+}
+
+// addWeighted appends value with the given count, giving the values
+// recorded so far a count of 1 if mt had no counts.
+func (mt *metric) addWeighted(value, count float64) {
+	if mt.counts == nil {
+		mt.counts = make([]float64, len(mt.values))
+		for i := range mt.counts {
+			mt.counts[i] = 1
+		}
+	}
+	mt.values = append(mt.values, value)
+	mt.counts = append(mt.counts, count)
+}
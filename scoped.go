@@ -0,0 +1,117 @@
+package emf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// scopedMetrics are metrics recorded under a dimension set of their own.
+type scopedMetrics struct {
+	dims    map[string]string
+	metrics map[string]*metric
+}
+
+// AddMetricForDimensions appends value to the named metric as recorded
+// under dims only, independently of the document's dimension sets and of
+// values recorded for the same key under other dimensions.
+//
+// EMF reads the values of every metric directive from the same root
+// member, so a metric name can carry only one value series per document.
+// Each distinct dims is therefore written as a document of its own, with a
+// single metric directive listing dims as its only dimension set, sharing
+// the namespace, properties and timestamp of m. Metrics recorded with
+// AddMetric are written in the first document. MarshalJSON and Resolve,
+// which describe a single document, leave the scoped metrics out;
+// ResolveAll includes them.
+func (m *CloudWatchMetric) AddMetricForDimensions(dims map[string]string, key string, unit Unit, value float64) {
+	mt := m.scopedMetric(dims, key, unit)
+	mt.values = append(mt.values, value)
+	if mt.counts != nil {
+		mt.counts = append(mt.counts, 1)
+	}
+}
+
+// scopedMetric returns the named metric recorded under dims, creating it
+// with unit if needed.
+func (m *CloudWatchMetric) scopedMetric(dims map[string]string, key string, unit Unit) *metric {
+	sig := dimensionSignature(dims)
+	if m.scoped == nil {
+		m.scoped = make(map[string]*scopedMetrics)
+	}
+	s, ok := m.scoped[sig]
+	if !ok {
+		s = &scopedMetrics{dims: copyDimensions(dims), metrics: make(map[string]*metric)}
+		m.scoped[sig] = s
+	}
+	mt, ok := s.metrics[key]
+	if !ok {
+		mt = &metric{unit: unit}
+		s.metrics[key] = mt
+	}
+	return mt
+}
+
+// defaultDimensionsWith returns a copy of the default dimension set with
+// key set to value, for recording a value under a dimension that must not
+// apply to the rest of the document.
+func (m *CloudWatchMetric) defaultDimensionsWith(key, value string) map[string]string {
+	var dims map[string]string
+	if len(m.dimensionSets) > 0 {
+		dims = copyDimensions(m.dimensionSets[0])
+	} else {
+		dims = make(map[string]string, 1)
+	}
+	dims[key] = value
+	return dims
+}
+
+// split returns the documents m is written as: the documents of
+// splitByTimestamp followed by one document per dimension set of the
+// scoped metrics, ordered by dimension signature. The first document is
+// left out if it has no metrics but scoped metrics exist.
+func (m *CloudWatchMetric) split() []*CloudWatchMetric {
+	docs := m.splitByTimestamp()
+	if len(m.scoped) == 0 {
+		return docs
+	}
+	if len(m.metrics) == 0 {
+		docs = nil
+	}
+	return append(docs, m.scopedDocuments()...)
+}
+
+// scopedDocuments returns one document per dimension set of the scoped
+// metrics, ordered by dimension signature.
+func (m *CloudWatchMetric) scopedDocuments() []*CloudWatchMetric {
+	sigs := make([]string, 0, len(m.scoped))
+	for sig := range m.scoped {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+
+	ts := m.effectiveTimestamp()
+	docs := make([]*CloudWatchMetric, 0, len(sigs))
+	for _, sig := range sigs {
+		s := m.scoped[sig]
+		c := m.clone()
+		c.timestamp = ts
+		c.dimensionSets = []map[string]string{copyDimensions(s.dims)}
+		c.primarySet, c.aggregateSet, c.scoped = nil, false, nil
+		c.metrics = make(map[string]*metric, len(s.metrics))
+		for k, mt := range s.metrics {
+			c.metrics[k] = mt.copy()
+		}
+		docs = append(docs, &c)
+	}
+	return docs
+}
+
+// validateScoped validates the documents of the scoped metrics.
+func (m *CloudWatchMetric) validateScoped() error {
+	for _, doc := range m.scopedDocuments() {
+		if err := doc.Validate(); err != nil {
+			return fmt.Errorf("%w (metrics for dimensions %v)", err, doc.dimensionSets[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package emf
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// SharedDocument combines several CloudWatchMetrics into one EMF document.
+// Each metric contributes its own metric directive, with its own namespace
+// and dimension sets, while properties are serialized once in the shared
+// root. This avoids repeating a large common property set across documents.
+//
+// Because every member lives in the same root object, a name may only
+// appear once with a given value: two metrics recording the same metric
+// name, or using the same dimension key with different values, cannot
+// share a document and MarshalJSON fails.
+type SharedDocument struct {
+	timestamp  time.Time
+	properties map[string]interface{}
+	metrics    []*CloudWatchMetric
+}
+
+// NewSharedDocument returns an empty shared document.
+func NewSharedDocument() *SharedDocument {
+	return &SharedDocument{properties: make(map[string]interface{})}
+}
+
+// SetTimestamp sets the document timestamp. Without it the time given by
+// AWS_EMF_TEST_TIMESTAMP or else the time of marshalling is used;
+// timestamps of the added metrics are ignored.
+func (d *SharedDocument) SetTimestamp(t time.Time) {
+	d.timestamp = t
+}
+
+// effectiveTimestamp returns the timestamp the document is marshalled
+// with, chosen as CloudWatchMetric chooses its own.
+func (d *SharedDocument) effectiveTimestamp() time.Time {
+	return effectiveTime(d.timestamp)
+}
+
+// AddProperty adds a property to the shared root.
+func (d *SharedDocument) AddProperty(key string, value interface{}) {
+	d.properties[key] = value
+}
+
+// AddProperties adds each entry of props to the shared root.
+func (d *SharedDocument) AddProperties(props map[string]interface{}) {
+	for k, v := range props {
+		d.properties[k] = v
+	}
+}
+
+// Add appends m as a metric directive of the document. The document keeps
+// a reference to m, so later changes to m are reflected when marshalling.
+func (d *SharedDocument) Add(m *CloudWatchMetric) {
+	d.metrics = append(d.metrics, m)
+}
+
+// MarshalJSON encodes the shared document in Embedded Metric Format. The
+// "_aws" member is written first, followed by the properties and dimension
+// values and then the metric values, each in lexical order.
+//
+// Each metric is resolved as its own MarshalJSON would resolve it, with
+// its options, properties and derived dimensions, at the timestamp of the
+// shared document. The metrics must agree on the EMF version they are
+// written with.
+func (d *SharedDocument) MarshalJSON() ([]byte, error) {
+	root := make(map[string]interface{}, len(d.properties))
+	for k, v := range d.properties {
+		root[k] = v
+	}
+	ts := d.effectiveTimestamp()
+	version := EMFVersion
+	directives := make([]ResolvedDirective, 0, len(d.metrics))
+	metrics := make(map[string]bool)
+	for i, m := range d.metrics {
+		if err := m.checkEncodable(); err != nil {
+			return nil, err
+		}
+		doc := m.encodingModel(ts)
+		if i == 0 {
+			version = doc.Version
+		} else if doc.Version != version {
+			return nil, fmt.Errorf("emf: shared document member %d: EMF version %q differs from %q", i, doc.Version, version)
+		}
+		for k, v := range doc.Root {
+			if prev, ok := root[k]; ok && !reflect.DeepEqual(prev, v) {
+				return nil, fmt.Errorf("emf: shared document member %d: %q conflicts with an existing root member", i, k)
+			}
+			root[k] = v
+		}
+		directives = append(directives, doc.Directives...)
+		metricMembers(metrics, doc.Directives, m.cfg.ValuesNamespace)
+	}
+
+	root["_aws"] = envelope{
+		Timestamp:         ts.UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: directives,
+		Version:           version,
+	}
+	return marshalRoot(orderRoot(root, MemberOrderEnvelopeFirst, metrics))
+}
+
+// Write marshals the document and writes it to w as a single
+// newline-terminated line.
+func (d *SharedDocument) Write(w io.Writer) error {
+	b, err := d.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
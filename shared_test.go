@@ -0,0 +1,72 @@
+package emf
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSharedDocumentResolvesMembers(t *testing.T) {
+	d := NewSharedDocument()
+	d.SetTimestamp(time.Unix(1700000000, 0))
+	d.AddProperty("RequestId", "r-1")
+	a := NewMetric("A", WithLevel("INFO"), WithRegion("eu-west-1"), WithEMFVersion("1"))
+	a.AddProperty("Env", "prod")
+	a.AddTemplatedDimension("Stage", "{Env}-api")
+	a.AddMetric("Latency", Milliseconds, 5)
+	b := NewMetric("B", WithEMFVersion("1"))
+	b.AddMetric("Requests", Count, 1)
+	d.Add(&a)
+	d.Add(&b)
+
+	out, err := d.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		AWS struct {
+			Version           string
+			CloudWatchMetrics []ResolvedDirective
+		} `json:"_aws"`
+		RequestId, Env, Stage, Region, Level string
+		Latency, Requests                    float64
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.AWS.Version != "1" || len(doc.AWS.CloudWatchMetrics) != 2 {
+		t.Errorf("_aws = %+v, want version 1 and two directives", doc.AWS)
+	}
+	if doc.RequestId != "r-1" || doc.Env != "prod" || doc.Stage != "prod-api" || doc.Region != "eu-west-1" || doc.Level != "INFO" {
+		t.Errorf("document %s lacks the members of its metrics", out)
+	}
+	if doc.Latency != 5 || doc.Requests != 1 {
+		t.Errorf("document %s lacks metric values", out)
+	}
+}
+
+func TestSharedDocumentConflicts(t *testing.T) {
+	d := NewSharedDocument()
+	a := NewMetric("A")
+	a.AddMetric("Requests", Count, 1)
+	b := NewMetric("B")
+	b.AddMetric("Requests", Count, 2)
+	d.Add(&a)
+	d.Add(&b)
+	if _, err := d.MarshalJSON(); err == nil {
+		t.Error("MarshalJSON() succeeded with two values for the same metric name")
+	}
+
+	d = NewSharedDocument()
+	c := NewMetric("C")
+	c.AddMetric("Value", Count, 1)
+	d.Add(&c)
+	bad := NewMetric("D")
+	bad.AddMetric("NaN", Count, math.NaN())
+	d.Add(&bad)
+	if _, err := d.MarshalJSON(); !errors.Is(err, ErrNonFiniteValue) {
+		t.Errorf("MarshalJSON() = %v, want ErrNonFiniteValue", err)
+	}
+}
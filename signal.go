@@ -0,0 +1,81 @@
+package emf
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FlushOnSignal starts a goroutine that waits for the first of sigs, by
+// default SIGTERM and SIGINT, and then calls flush with a context ending
+// after timeout, so that metrics buffered at shutdown are written before
+// the process exits, as when a container is stopped. Once flush returns,
+// or the timeout passes if it does not return in time, the handler is
+// removed and the signal raised again, so that the process terminates as
+// it would have without the handler. Other handlers installed for the
+// signal with signal.Notify therefore receive it twice. Calling the
+// returned function removes the handler without flushing.
+//
+// Use it with any aggregating component:
+//
+//	defer emf.FlushOnSignal(5*time.Second, func(context.Context) {
+//		collector.Flush(sink)
+//	})()
+func FlushOnSignal(timeout time.Duration, flush func(ctx context.Context), sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	quit := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-ch:
+		case <-quit:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			flush(ctx)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		stop()
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(sig)
+		}
+	}()
+	return stop
+}
+
+// FlushOnSignal flushes the document held back by WithCoalesce when the
+// process receives one of sigs, as the package-level FlushOnSignal does.
+// Write failures are reported as for Emit.
+func (e *Emitter) FlushOnSignal(timeout time.Duration, sigs ...os.Signal) (stop func()) {
+	return FlushOnSignal(timeout, func(context.Context) { e.Flush() }, sigs...)
+}
+
+// FlushOnSignal closes the emitter, writing the buffered documents, when
+// the process receives one of sigs, as the package-level FlushOnSignal
+// does. Documents not written within timeout are dropped; failures are
+// reported through the emitter's Stats and error handler.
+func (b *BufferedEmitter) FlushOnSignal(timeout time.Duration, sigs ...os.Signal) (stop func()) {
+	return FlushOnSignal(timeout, func(ctx context.Context) { b.CloseContext(ctx) }, sigs...)
+}
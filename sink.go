@@ -0,0 +1,38 @@
+package emf
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink is a destination for serialized EMF documents. Each call to Emit
+// receives exactly one newline-terminated JSON document. Implementations
+// must be safe for concurrent use.
+type Sink interface {
+	Emit(doc []byte) error
+}
+
+// WriterSink writes documents to an io.Writer, serializing concurrent calls
+// so that documents are never interleaved.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a Sink that writes each document to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Emit writes doc to the underlying writer.
+func (s *WriterSink) Emit(doc []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(doc)
+	return err
+}
+
+// Stdout is a Sink writing to standard output, where the CloudWatch agent
+// and AWS Lambda pick up EMF documents.
+var Stdout Sink = NewWriterSink(os.Stdout)
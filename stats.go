@@ -0,0 +1,56 @@
+package emf
+
+import "sync/atomic"
+
+// Stats describes an emitter's activity since it was created.
+type Stats struct {
+	// Emitted is the number of documents accepted by the sink.
+	Emitted uint64
+	// Bytes is the total size of the emitted documents.
+	Bytes uint64
+	// Errors is the number of documents that failed to marshal or that the
+	// sink rejected.
+	Errors uint64
+	// Dropped is the number of buffered documents discarded on close.
+	Dropped uint64
+	// RateLimited is the number of documents discarded by WithRateLimit.
+	RateLimited uint64
+}
+
+// emitterStats holds the counters behind Stats.
+type emitterStats struct {
+	emitted     uint64
+	bytes       uint64
+	errors      uint64
+	dropped     uint64
+	rateLimited uint64
+}
+
+// WithErrorHandler makes the emitter call fn with every marshalling or sink
+// error, in addition to counting it in Stats. For a BufferedEmitter this is
+// the only way to learn about individual write failures. fn must be safe
+// for concurrent use.
+func WithErrorHandler(fn func(error)) EmitterOption {
+	return func(e *Emitter) {
+		e.onError = fn
+	}
+}
+
+// Stats returns counters describing the emitter's activity so far.
+func (e *Emitter) Stats() Stats {
+	return Stats{
+		Emitted:     atomic.LoadUint64(&e.stats.emitted),
+		Bytes:       atomic.LoadUint64(&e.stats.bytes),
+		Errors:      atomic.LoadUint64(&e.stats.errors),
+		Dropped:     atomic.LoadUint64(&e.stats.dropped),
+		RateLimited: atomic.LoadUint64(&e.stats.rateLimited),
+	}
+}
+
+// failed records an emit failure.
+func (e *Emitter) failed(err error) {
+	atomic.AddUint64(&e.stats.errors, 1)
+	if e.onError != nil {
+		e.onError(err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package statsd formats EMF documents as StatsD lines, for hosts where the
+// CloudWatch agent ingests metrics through its StatsD endpoint rather than
+// by extracting them from logs. It keeps the core package independent of
+// the StatsD format.
+//
+// The agent assigns the namespace from its own configuration, so the
+// document namespace is not part of the output. Statistic sets cannot be
+// expressed in StatsD and are skipped.
+package statsd
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+// Lines returns the StatsD lines for every document m is written as,
+// scoped metrics included, without line terminators: one per value and
+// dimension set, of the form name:value|type|#dim:val,... Count
+// metrics become counters, durations become timers in milliseconds and
+// everything else becomes gauges. Values recorded with a count greater than
+// one carry the equivalent sample rate.
+func Lines(m *emf.CloudWatchMetric) []string {
+	var lines []string
+	for _, doc := range m.ResolveAll() {
+		lines = append(lines, documentLines(doc)...)
+	}
+	return lines
+}
+
+// documentLines returns the StatsD lines for a single document.
+func documentLines(doc emf.ResolvedDocument) []string {
+	var lines []string
+	for _, directive := range doc.Directives {
+		for _, def := range directive.Metrics {
+			values, counts := lookupValues(doc.Root, def.Name)
+			typ, scale := statType(def.Unit)
+			for _, keys := range directive.Dimensions {
+				tags := tagSuffix(doc.Root, keys)
+				for i, v := range values {
+					var b strings.Builder
+					b.WriteString(sanitize(def.Name))
+					b.WriteByte(':')
+					b.WriteString(strconv.FormatFloat(v*scale, 'g', -1, 64))
+					b.WriteByte('|')
+					b.WriteString(typ)
+					if counts != nil && counts[i] > 1 {
+						b.WriteString("|@")
+						b.WriteString(strconv.FormatFloat(1/counts[i], 'g', -1, 64))
+					}
+					b.WriteString(tags)
+					lines = append(lines, b.String())
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// Write writes the lines for m to w, each newline-terminated and in a Write
+// call of its own, so that w may be a UDP connection to the agent sending
+// one datagram per line.
+func Write(w io.Writer, m *emf.CloudWatchMetric) error {
+	for _, line := range Lines(m) {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statType returns the StatsD metric type for unit and the factor that
+// converts values into the units StatsD expects.
+func statType(unit emf.Unit) (string, float64) {
+	switch unit {
+	case emf.Count:
+		return "c", 1
+	case emf.Milliseconds:
+		return "ms", 1
+	case emf.Seconds:
+		return "ms", 1000
+	case emf.Microseconds:
+		return "ms", 1.0 / 1000
+	}
+	return "g", 1
+}
+
+// tagSuffix returns the "|#key:value,..." suffix for the dimension keys,
+// or the empty string if there are none.
+func tagSuffix(root map[string]interface{}, keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("|#")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(sanitize(k))
+		b.WriteByte(':')
+		if v, ok := root[k].(string); ok {
+			b.WriteString(sanitize(v))
+		}
+	}
+	return b.String()
+}
+
+// sanitize replaces the characters that delimit StatsD fields.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// lookupValues returns the values and value counts of the metric name in
+// root, following a dotted path into nested objects if name is not a root
+// member. Non-finite values are dropped.
+func lookupValues(root map[string]interface{}, name string) ([]float64, []float64) {
+	v, ok := root[name]
+	if !ok {
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			if nested, ok := root[name[:i]].(map[string]interface{}); ok {
+				return lookupValues(nested, name[i+1:])
+			}
+		}
+		return nil, nil
+	}
+	var values, counts []float64
+	switch v := v.(type) {
+	case float64:
+		values = []float64{v}
+	case []float64:
+		values = v
+	case emf.WeightedValues:
+		values, counts = v.Values, v.Counts
+	}
+	return finite(values, counts)
+}
+
+// finite returns the finite values and their counts.
+func finite(values, counts []float64) ([]float64, []float64) {
+	var fv, fc []float64
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		fv = append(fv, v)
+		if counts != nil {
+			fc = append(fc, counts[i])
+		}
+	}
+	return fv, fc
+}
@@ -0,0 +1,20 @@
+package statsd
+
+import (
+	"reflect"
+	"testing"
+
+	emf "github.com/codasols/aws-emf"
+)
+
+func TestLinesIncludeScopedMetrics(t *testing.T) {
+	m := emf.NewMetric("NS")
+	m.AddMetric("Latency", emf.Milliseconds, 5)
+	m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Requests", emf.Count, 1)
+
+	got := Lines(&m)
+	want := []string{"Latency:5|ms", "Requests:1|c|#Operation:Get"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package emf
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// StatisticSet is a pre-aggregated summary of a metric's values, emitted in
+// place of the individual values.
+type StatisticSet struct {
+	Min         float64 `json:"Min"`
+	Max         float64 `json:"Max"`
+	Sum         float64 `json:"Sum"`
+	SampleCount float64 `json:"SampleCount"`
+}
+
+// merge returns the statistic set summarizing both s and o.
+func (s StatisticSet) merge(o StatisticSet) StatisticSet {
+	return StatisticSet{
+		Min:         math.Min(s.Min, o.Min),
+		Max:         math.Max(s.Max, o.Max),
+		Sum:         s.Sum + o.Sum,
+		SampleCount: s.SampleCount + o.SampleCount,
+	}
+}
+
+// validate reports whether CloudWatch would accept s.
+func (s StatisticSet) validate() error {
+	for _, v := range []float64{s.Min, s.Max, s.Sum, s.SampleCount} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("has non-finite value %v", v)
+		}
+	}
+	if s.SampleCount <= 0 {
+		return fmt.Errorf("has sample count %v, must be positive", s.SampleCount)
+	}
+	if s.Min > s.Max {
+		return fmt.Errorf("has minimum %v above maximum %v", s.Min, s.Max)
+	}
+	return nil
+}
+
+// summarize returns the statistic set of values, which must not be empty,
+// weighted by counts if it is not nil.
+func summarize(values, counts []float64) StatisticSet {
+	s := StatisticSet{Min: values[0], Max: values[0]}
+	for i, v := range values {
+		n := 1.0
+		if counts != nil {
+			n = counts[i]
+		}
+		s = s.merge(StatisticSet{Min: v, Max: v, Sum: v * n, SampleCount: n})
+	}
+	return s
+}
+
+// timedStatisticSet is a statistic set recorded for a specific timestamp.
+// A zero timestamp means the timestamp of the document.
+type timedStatisticSet struct {
+	timestamp time.Time
+	set       StatisticSet
+}
+
+// AddStatisticSet records a pre-aggregated statistic set for the metric.
+// Sets recorded for the same metric and timestamp are merged, as are any
+// values added with AddMetric, so the metric is emitted as a single set.
+//
+// An optional timestamp places the set in a time window of its own, for
+// importing historical aggregates: when the document is written, each
+// distinct timestamp that differs from the document's produces a separate
+// document carrying only the statistic sets for that timestamp, alongside
+// the document's dimensions and properties. MarshalJSON, which encodes a
+// single document, leaves such sets out.
+func (m *CloudWatchMetric) AddStatisticSet(key string, unit Unit, set StatisticSet, timestamp ...time.Time) {
+	m.lazyInit()
+	var ts time.Time
+	if len(timestamp) > 0 {
+		ts = timestamp[0]
+	}
+	mt, ok := m.metrics[key]
+	if !ok {
+		mt = &metric{unit: unit, kind: KindStatisticSet}
+		m.metrics[key] = mt
+	}
+	for i, s := range mt.statSets {
+		if s.timestamp.Equal(ts) {
+			mt.statSets[i].set = s.set.merge(set)
+			return
+		}
+	}
+	mt.statSets = append(mt.statSets, timedStatisticSet{timestamp: ts, set: set})
+}
+
+// statisticSet returns the statistic set to emit for mt in a document with
+// timestamp ts, combining its values and the sets recorded for ts, and
+// whether mt has any set for ts.
+func (mt *metric) statisticSet(ts time.Time) (StatisticSet, bool) {
+	var sum StatisticSet
+	found := false
+	for _, s := range mt.statSets {
+		if !s.timestamp.IsZero() && !s.timestamp.Equal(ts) {
+			continue
+		}
+		if found {
+			sum = sum.merge(s.set)
+		} else {
+			sum, found = s.set, true
+		}
+	}
+	if found && len(mt.values) > 0 {
+		sum = sum.merge(summarize(mt.values, mt.counts))
+	}
+	return sum, found
+}
+
+// splitByTimestamp returns the documents m is written as: m itself, plus
+// one document per distinct statistic set timestamp other than the
+// document's. If no split is needed the result is m alone, uncopied.
+func (m *CloudWatchMetric) splitByTimestamp() []*CloudWatchMetric {
+	ts := m.effectiveTimestamp()
+	var others []time.Time
+	seen := make(map[int64]bool)
+	for _, mt := range m.metrics {
+		for _, s := range mt.statSets {
+			if s.timestamp.IsZero() || s.timestamp.Equal(ts) || seen[s.timestamp.UnixNano()] {
+				continue
+			}
+			seen[s.timestamp.UnixNano()] = true
+			others = append(others, s.timestamp)
+		}
+	}
+	if len(others) == 0 {
+		return []*CloudWatchMetric{m}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Before(others[j]) })
+
+	base := m.clone()
+	base.timestamp = ts
+	docs := []*CloudWatchMetric{&base}
+	for name, mt := range base.metrics {
+		if len(mt.values) == 0 && !hasStatSetAt(mt, ts) {
+			delete(base.metrics, name)
+		}
+	}
+	for _, other := range others {
+		c := m.clone()
+		c.timestamp = other
+		for name, mt := range c.metrics {
+			var sets []timedStatisticSet
+			for _, s := range mt.statSets {
+				if s.timestamp.Equal(other) {
+					sets = append(sets, s)
+				}
+			}
+			if len(sets) == 0 {
+				delete(c.metrics, name)
+				continue
+			}
+			mt.values, mt.counts, mt.statSets = nil, nil, sets
+		}
+		docs = append(docs, &c)
+	}
+	return docs
+}
+
+// hasStatSetAt reports whether mt has a statistic set for a document with
+// timestamp ts.
+func hasStatSetAt(mt *metric, ts time.Time) bool {
+	for _, s := range mt.statSets {
+		if s.timestamp.IsZero() || s.timestamp.Equal(ts) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package emf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithStrictTemplates makes MarshalJSON and Validate fail when a templated
+// dimension refers to a property the document does not have. By default
+// such placeholders are replaced with the empty string.
+func WithStrictTemplates() Option {
+	return func(c *Config) {
+		c.StrictTemplates = true
+	}
+}
+
+// AddTemplatedDimension adds a dimension to the default dimension set whose
+// value is computed from template when the document is marshalled. Each
+// {name} placeholder is replaced with the value of the property name, so
+// that with properties env=prod and region=eu-west-1 the template
+// "{env}-{region}" yields "prod-eu-west-1". Text outside braces is copied
+// as is.
+func (m *CloudWatchMetric) AddTemplatedDimension(key, template string) {
+	if m.templates == nil {
+		m.templates = make(map[string]string)
+	}
+	m.templates[key] = template
+}
+
+// checkTemplates returns an error for unresolved placeholders under
+// WithStrictTemplates.
+func (m *CloudWatchMetric) checkTemplates() error {
+	if !m.cfg.StrictTemplates {
+		return nil
+	}
+	for _, key := range sortedKeys(m.templates) {
+		if _, missing := renderTemplate(m.templates[key], m.properties); len(missing) > 0 {
+			return fieldErrorf("templates."+key, ErrInvalidDimensionValue, "emf: templated dimension %q refers to missing properties %s", key, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// renderTemplate substitutes the {name} placeholders of tmpl with property
+// values. It returns the result and the sorted names of placeholders with
+// no matching property, which are replaced with the empty string.
+func renderTemplate(tmpl string, props map[string]interface{}) (string, []string) {
+	var b strings.Builder
+	var missing []string
+	for {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(tmpl[open:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(tmpl[:open])
+		name := tmpl[open+1 : open+end]
+		if v, ok := props[name]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			missing = append(missing, name)
+		}
+		tmpl = tmpl[open+end+1:]
+	}
+	b.WriteString(tmpl)
+	sort.Strings(missing)
+	return b.String(), missing
+}
@@ -0,0 +1,30 @@
+package emf
+
+import "math"
+
+// AddMetricWithThresholdDimension records value for the named metric under
+// the default dimension set plus the dimension dimKey, set to the label of
+// the threshold bucket value falls into. The value is recorded with
+// AddMetricForDimensions, so each value carries its own label and values
+// of different buckets can be recorded in one document; the default
+// dimension set is taken as it is when the method is called.
+//
+// Each threshold is the inclusive lower bound of its bucket: the label of
+// the greatest threshold less than or equal to value is chosen. With
+// thresholds {0: "Low", 500: "Medium", 1000: "High"}, 499.9 is Low, 500 is
+// Medium and 1000 is High. If value is below every threshold it is
+// recorded like AddMetric, without the dimension.
+func (m *CloudWatchMetric) AddMetricWithThresholdDimension(key string, unit Unit, value float64, dimKey string, thresholds map[float64]string) {
+	best, label := math.Inf(-1), ""
+	found := false
+	for t, l := range thresholds {
+		if t <= value && (!found || t > best) {
+			best, label, found = t, l, true
+		}
+	}
+	if !found {
+		m.AddMetric(key, unit, value)
+		return
+	}
+	m.AddMetricForDimensions(m.defaultDimensionsWith(dimKey, label), key, unit, value)
+}
@@ -0,0 +1,29 @@
+package emf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddMetricWithThresholdDimension(t *testing.T) {
+	thresholds := map[float64]string{0: "Low", 500: "Medium", 1000: "High"}
+	m := NewMetric("NS")
+	m.AddDimension("Service", "api")
+	m.AddMetricWithThresholdDimension("Latency", Milliseconds, 10, "Severity", thresholds)
+	m.AddMetricWithThresholdDimension("Latency", Milliseconds, 2000, "Severity", thresholds)
+	m.AddMetricWithThresholdDimension("Latency", Milliseconds, 500, "Severity", thresholds)
+	m.AddMetricWithThresholdDimension("Latency", Milliseconds, -1, "Severity", thresholds)
+
+	got := make(map[string]interface{})
+	for _, doc := range m.ResolveAll() {
+		severity, _ := doc.Root["Severity"].(string)
+		got[severity] = doc.Root["Latency"]
+		if doc.Root["Service"] != "api" {
+			t.Errorf("document %v lacks the default dimensions", doc.Root)
+		}
+	}
+	want := map[string]interface{}{"": -1.0, "Low": 10.0, "Medium": 500.0, "High": 2000.0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Latency by Severity = %v, want %v", got, want)
+	}
+}
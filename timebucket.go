@@ -0,0 +1,21 @@
+package emf
+
+import "time"
+
+// WithTimeBucketDimension adds a dimension named key to the default
+// dimension set whose value is the document timestamp truncated to
+// granularity, formatted as RFC 3339 in UTC. For example, with a
+// granularity of time.Minute a document stamped 12:34:56 carries
+// key=2006-01-02T12:34:00Z. The bucket is derived when the document is
+// marshalled, from the same timestamp that is emitted.
+func WithTimeBucketDimension(key string, granularity time.Duration) Option {
+	return func(c *Config) {
+		c.TimeBucketDimension = key
+		c.TimeBucketGranularity = granularity
+	}
+}
+
+// timeBucket returns the time bucket dimension value for the timestamp ts.
+func (m *CloudWatchMetric) timeBucket(ts time.Time) string {
+	return ts.UTC().Truncate(m.cfg.TimeBucketGranularity).Format(time.RFC3339)
+}
@@ -0,0 +1,71 @@
+package emf
+
+import (
+	"sort"
+	"time"
+)
+
+// The window around the current time within which CloudWatch accepts
+// metric timestamps. Metrics outside it are dropped without an error.
+var (
+	DefaultMaxTimestampAge  = 2 * time.Hour
+	DefaultMaxTimestampLead = 15 * time.Minute
+)
+
+// WithTimestampWindow sets how far the document timestamp may be in the
+// past (maxAge) or in the future (maxLead) before Validate reports it. A
+// zero duration keeps DefaultMaxTimestampAge or DefaultMaxTimestampLead;
+// use it if CloudWatch changes the window it accepts.
+func WithTimestampWindow(maxAge, maxLead time.Duration) Option {
+	return func(c *Config) {
+		c.MaxTimestampAge = maxAge
+		c.MaxTimestampLead = maxLead
+	}
+}
+
+// checkTimestamps returns an error if the document timestamp or that of a
+// statistic set is outside the accepted window around now. Unset
+// timestamps are taken at marshalling and always accepted.
+func (m *CloudWatchMetric) checkTimestamps(now time.Time) error {
+	if err := m.checkTimestamp(m.timestamp, now); err != nil {
+		return err
+	}
+	for _, name := range sortedMetricNames(m.metrics) {
+		for _, s := range m.metrics[name].statSets {
+			if err := m.checkTimestamp(s.timestamp, now); err != nil {
+				return fieldErrorf("metrics."+name, ErrTimestampOutOfRange, "emf: statistic set of metric %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *CloudWatchMetric) checkTimestamp(ts, now time.Time) error {
+	if ts.IsZero() {
+		return nil
+	}
+	maxAge, maxLead := m.cfg.MaxTimestampAge, m.cfg.MaxTimestampLead
+	if maxAge == 0 {
+		maxAge = DefaultMaxTimestampAge
+	}
+	if maxLead == 0 {
+		maxLead = DefaultMaxTimestampLead
+	}
+	if d := now.Sub(ts); d > maxAge {
+		return fieldErrorf("timestamp", ErrTimestampOutOfRange, "emf: timestamp %s is %s in the past, more than the %s CloudWatch accepts", ts.Format(time.RFC3339), d.Round(time.Second), maxAge)
+	}
+	if d := ts.Sub(now); d > maxLead {
+		return fieldErrorf("timestamp", ErrTimestampOutOfRange, "emf: timestamp %s is %s in the future, more than the %s CloudWatch accepts", ts.Format(time.RFC3339), d.Round(time.Second), maxLead)
+	}
+	return nil
+}
+
+// sortedMetricNames returns the names of metrics in lexical order.
+func sortedMetricNames(metrics map[string]*metric) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
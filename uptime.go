@@ -0,0 +1,14 @@
+package emf
+
+import "time"
+
+// processStart approximates the start of the process: the time the package
+// was initialized.
+var processStart = time.Now()
+
+// AddUptime records the time since the process started, in Seconds, as the
+// key metric. The start is taken when the package is initialized, which in
+// practice is within moments of the process starting.
+func (m *CloudWatchMetric) AddUptime(key string) {
+	m.AddMetric(key, Seconds, time.Since(processStart).Seconds())
+}
@@ -0,0 +1,194 @@
+package emf
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validate reports the first reason CloudWatch would reject the document.
+// Limit violations are only reported under PolicyError; other policies
+// truncate the document when it is marshalled. Validate marshals the
+// document to check its size against MaxEventBytes; MarshalJSON does not
+// call Validate. The error wraps one of the Err variables of this package;
+// use ValidateAll to get every reason.
+func (m *CloudWatchMetric) Validate() error {
+	if errs := m.validate(true); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateAll is like Validate but returns a *ValidationError holding
+// every reason CloudWatch would reject the document, at most one per
+// document-wide check and one per metric check. The size of the document
+// is only checked if it is otherwise valid.
+func (m *CloudWatchMetric) ValidateAll() error {
+	if errs := m.validate(false); len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validate runs the checks of Validate and returns the failures, stopping
+// at the first if first is set.
+func (m *CloudWatchMetric) validate(first bool) []error {
+	var errs []error
+	fail := func(found ...error) bool {
+		errs = append(errs, found...)
+		return first && len(errs) > 0
+	}
+	if err := validateNamespace(m.namespace); err != nil && fail(err) {
+		return errs
+	}
+	if m.cfg.LimitPolicy == PolicyError {
+		if fail(m.limitViolations()...) {
+			return errs
+		}
+	} else if m.cfg.StrictDimensionKeys {
+		if fail(m.dimensionKeyViolations()...) {
+			return errs
+		}
+	}
+	for _, check := range []func() error{
+		func() error { return m.checkTimestamps(time.Now()) },
+		m.checkDimensionValues,
+		m.checkDimensionConflicts,
+		m.checkTemplates,
+	} {
+		if err := check(); err != nil && fail(err) {
+			return errs
+		}
+	}
+
+	names := make([]string, 0, len(m.metrics))
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := m.validateMetric(name); err != nil && fail(err) {
+			return errs
+		}
+	}
+	if err := m.validateScoped(); err != nil && fail(err) {
+		return errs
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if err := m.checkEventSize(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// IsValid reports whether CloudWatch would accept the document, that is
+// whether Validate returns nil.
+func (m *CloudWatchMetric) IsValid() bool {
+	return m.Validate() == nil
+}
+
+// validateMetric returns the first reason CloudWatch would reject the
+// named metric.
+func (m *CloudWatchMetric) validateMetric(name string) error {
+	mt := m.metrics[name]
+	field := "metrics." + name
+	if name == "" {
+		return fieldErrorf(field, ErrEmptyName, "emf: metric name is empty")
+	}
+	if !mt.unit.Valid() {
+		return fieldErrorf(field, ErrInvalidUnit, "emf: metric %q has invalid unit %q", name, mt.unit)
+	}
+	if v, ok := nonFinite(mt.values); ok {
+		return fieldErrorf(field, ErrNonFiniteValue, "emf: metric %q has non-finite value %v", name, v)
+	}
+	for _, s := range mt.statSets {
+		if err := s.set.validate(); err != nil {
+			kind := ErrInvalidStatisticSet
+			if _, ok := nonFinite([]float64{s.set.Min, s.set.Max, s.set.Sum, s.set.SampleCount}); ok {
+				kind = ErrNonFiniteValue
+			}
+			return fieldErrorf(field, kind, "emf: statistic set of metric %q %v", name, err)
+		}
+	}
+	if _, ok := m.properties[name]; ok {
+		return fieldErrorf(field, ErrNameCollision, "emf: metric %q collides with a property of the same name", name)
+	}
+	for _, set := range m.emittedDimensionSets() {
+		if _, ok := set[name]; ok {
+			return fieldErrorf(field, ErrNameCollision, "emf: metric %q collides with a dimension of the same name", name)
+		}
+	}
+	return nil
+}
+
+// checkEventSize marshals the document and returns an error if any of the
+// log events it is written as exceeds MaxEventBytes.
+func (m *CloudWatchMetric) checkEventSize() error {
+	lines, err := m.marshalLines()
+	if err != nil {
+		return err
+	}
+	for _, b := range lines {
+		if len(b) > MaxEventBytes {
+			return fieldErrorf("document", ErrDocumentTooLarge, "emf: document of %d bytes exceeds the limit of %d", len(b), MaxEventBytes)
+		}
+	}
+	return nil
+}
+
+// checkFinite returns an error naming the first metric with a NaN or
+// infinite value or statistic, which JSON cannot represent. MarshalJSON
+// calls it so that such documents fail with a useful message rather than
+// the generic one of encoding/json.
+func (m *CloudWatchMetric) checkFinite() error {
+	names := make([]string, 0, len(m.metrics))
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mt := m.metrics[name]
+		field := "metrics." + name
+		if v, ok := nonFinite(mt.values); ok {
+			return fieldErrorf(field, ErrNonFiniteValue, "emf: metric %q has non-finite value %v", name, v)
+		}
+		if v, ok := nonFinite(mt.counts); ok {
+			return fieldErrorf(field, ErrNonFiniteValue, "emf: metric %q has non-finite count %v", name, v)
+		}
+		for _, s := range mt.statSets {
+			set := s.set
+			if v, ok := nonFinite([]float64{set.Min, set.Max, set.Sum, set.SampleCount}); ok {
+				return fieldErrorf(field, ErrNonFiniteValue, "emf: statistic set of metric %q has non-finite value %v", name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// nonFinite returns the first NaN or infinite value in values.
+func nonFinite(values []float64) (float64, bool) {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// validateNamespace checks a namespace against the CloudWatch rules.
+func validateNamespace(ns string) error {
+	if ns == "" {
+		return fieldErrorf("namespace", ErrEmptyName, "emf: namespace is empty")
+	}
+	if n := utf8.RuneCountInString(ns); n > MaxNamespaceLength {
+		return fieldErrorf("namespace", ErrInvalidNamespace, "emf: namespace of %d characters exceeds the limit of %d", n, MaxNamespaceLength)
+	}
+	if strings.HasPrefix(ns, "AWS/") {
+		return fieldErrorf("namespace", ErrReservedNamespace, "emf: namespace %q uses the reserved AWS/ prefix", ns)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package emf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateDimensionMetricCollision(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Latency", "p99")
+	m.AddMetric("Latency", Milliseconds, 5)
+
+	err := m.Validate()
+	if !errors.Is(err, ErrNameCollision) {
+		t.Fatalf("Validate() = %v, want ErrNameCollision", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "metrics.Latency" {
+		t.Errorf("Validate() = %v, want a FieldError for metrics.Latency", err)
+	}
+}
+
+func TestValidatePropertyMetricCollision(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddProperty("Latency", "slow")
+	m.AddMetric("Latency", Milliseconds, 5)
+	if err := m.Validate(); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("Validate() = %v, want ErrNameCollision", err)
+	}
+}
+
+func TestValidateNoCollision(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Operation", "Get")
+	m.AddMetric("Latency", Milliseconds, 5)
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
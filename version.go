@@ -0,0 +1,24 @@
+package emf
+
+// EMFVersion is the version of the Embedded Metric Format specification the
+// package implements, emitted as the Version of the "_aws" member.
+const EMFVersion = "0"
+
+// WithEMFVersion overrides the Version emitted in the "_aws" member, for
+// specification versions newer than the package. CloudWatch rejects
+// documents with a version it does not know, so leave it unset unless AWS
+// documents a new version.
+func WithEMFVersion(v string) Option {
+	return func(c *Config) {
+		c.EMFVersion = v
+	}
+}
+
+// emfVersion returns the specification version the document is emitted
+// with.
+func (m *CloudWatchMetric) emfVersion() string {
+	if m.cfg.EMFVersion != "" {
+		return m.cfg.EMFVersion
+	}
+	return EMFVersion
+}
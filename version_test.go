@@ -0,0 +1,46 @@
+package emf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// envelopeVersion returns the Version of the "_aws" member of m.
+func envelopeVersion(t *testing.T, m *CloudWatchMetric) string {
+	t.Helper()
+	b, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		AWS struct {
+			Version *string
+		} `json:"_aws"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.AWS.Version == nil {
+		t.Fatalf("%s has no _aws.Version", b)
+	}
+	return *doc.AWS.Version
+}
+
+func TestDefaultEMFVersion(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddMetric("Latency", Milliseconds, 5)
+	if got := envelopeVersion(t, &m); got != "0" {
+		t.Errorf("_aws.Version = %q, want the current specification version \"0\"", got)
+	}
+}
+
+func TestWithEMFVersion(t *testing.T) {
+	m := NewMetric("NS", WithEMFVersion("1"))
+	m.AddMetric("Latency", Milliseconds, 5)
+	if got := envelopeVersion(t, &m); got != "1" {
+		t.Errorf("_aws.Version = %q, want \"1\"", got)
+	}
+	if got := m.Resolve().Version; got != "1" {
+		t.Errorf("Resolve().Version = %q, want \"1\"", got)
+	}
+}
@@ -0,0 +1,85 @@
+package emf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// warnFraction is the fraction of a CloudWatch limit from which Warnings
+// reports a document as approaching it.
+const warnFraction = 0.8
+
+// minIDLength is the length from which Warnings takes a dimension value
+// made of hexadecimal digits and dashes for a unique identifier.
+const minIDLength = 16
+
+// Warnings returns advisories about a document that CloudWatch would
+// accept but that is at risk: a metric count, value count or dimension set
+// count at or above 80% of its limit, dimension values that look like
+// unique identifiers such as UUIDs or hashes, which make every document a
+// metric of its own, and properties with empty values. Unlike Validate it
+// never fails and reports every finding, in a stable order. It returns nil
+// if there is nothing to report.
+func (m *CloudWatchMetric) Warnings() []string {
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if n := len(m.metrics); nearLimit(n, MaxMetrics) {
+		warn("document has %d metrics, near or above the limit of %d", n, MaxMetrics)
+	}
+	for _, name := range sortedMetricNames(m.metrics) {
+		if n := len(m.metrics[name].values); nearLimit(n, MaxValuesPerMetric) {
+			warn("metric %q has %d values, near or above the limit of %d", name, n, MaxValuesPerMetric)
+		}
+	}
+	sets := m.emittedDimensionSets()
+	if n := len(sets); nearLimit(n, MaxDimensionSets) {
+		warn("document has %d dimension sets, near or above the limit of %d", n, MaxDimensionSets)
+	}
+	reported := make(map[string]bool)
+	for _, set := range sets {
+		for _, k := range sortedKeys(set) {
+			if v := set[k]; !reported[k] && looksLikeID(v) {
+				reported[k] = true
+				warn("dimension %q has value %q, which looks like a unique identifier and makes the dimension high-cardinality", k, v)
+			}
+		}
+	}
+	for _, k := range sortedPropertyKeys(m.properties) {
+		if isEmptyValue(m.properties[k]) {
+			warn("property %q has an empty value", k)
+		}
+	}
+	return warnings
+}
+
+// nearLimit reports whether n is at or above warnFraction of limit.
+func nearLimit(n, limit int) bool {
+	return float64(n) >= warnFraction*float64(limit)
+}
+
+// looksLikeID reports whether v looks like a unique identifier: at least
+// minIDLength hexadecimal digits and dashes, including a digit.
+func looksLikeID(v string) bool {
+	if len(v) < minIDLength || !strings.ContainsAny(v, "0123456789") {
+		return false
+	}
+	for _, r := range v {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f', r >= 'A' && r <= 'F', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyValue reports whether a property value is nil or an empty string.
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && s == ""
+}
@@ -0,0 +1,68 @@
+package emf
+
+// SetWindowed marks the named metric as windowed: EndWindow clears its
+// values while leaving those of other metrics to accumulate. The metric
+// need not have been recorded yet, and the mark survives ReplaceMetric.
+func (m *CloudWatchMetric) SetWindowed(key string) {
+	if m.windowed == nil {
+		m.windowed = make(map[string]bool)
+	}
+	m.windowed[key] = true
+}
+
+// Snapshot returns an independent copy of the document, including its
+// windowed marks. Neither the document nor the copy is affected by changes
+// to the other.
+func (m *CloudWatchMetric) Snapshot() CloudWatchMetric {
+	return m.clone()
+}
+
+// Reset clears the values of every metric, windowed or not, keeping their
+// units along with the dimensions and properties of the document. Metrics
+// without values are left out when the document is marshalled. Metrics
+// recorded with AddMetricForDimensions are discarded with their dimension
+// sets, so that documents are only written for the sets recorded again.
+func (m *CloudWatchMetric) Reset() {
+	for _, mt := range m.metrics {
+		mt.clear()
+	}
+	m.scoped = nil
+}
+
+// EndWindow closes an aggregation window: it returns a Snapshot of the
+// document as it stands and then clears the values of the metrics marked
+// with SetWindowed, so that the next window starts fresh for them while
+// the other metrics keep accumulating. Emit the returned snapshot.
+//
+// Marked metrics recorded with AddMetricForDimensions are discarded, and
+// with them the dimension sets left without metrics.
+func (m *CloudWatchMetric) EndWindow() CloudWatchMetric {
+	snap := m.Snapshot()
+	for key := range m.windowed {
+		if mt, ok := m.metrics[key]; ok {
+			mt.clear()
+		}
+	}
+	for sig, s := range m.scoped {
+		for key := range s.metrics {
+			if m.windowed[key] {
+				delete(s.metrics, key)
+			}
+		}
+		if len(s.metrics) == 0 {
+			delete(m.scoped, sig)
+		}
+	}
+	return snap
+}
+
+// clear discards the recorded values and RecordOnce keys, keeping the unit
+// and whether values are weighted.
+func (mt *metric) clear() {
+	mt.values = nil
+	mt.seen = nil
+	if mt.counts != nil {
+		mt.counts = mt.counts[:0]
+	}
+	mt.statSets = nil
+}
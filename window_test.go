@@ -0,0 +1,57 @@
+package emf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestResetClearsScopedMetrics(t *testing.T) {
+	m := NewMetric("NS")
+	m.AddDimension("Service", "api")
+	m.AddMetric("Latency", Milliseconds, 12)
+	m.RecordOutcome("Get", nil)
+	m.AddMetricForDimensions(map[string]string{"Operation": "Put"}, "Requests", Count, 1)
+	m.Reset()
+
+	if docs := m.ResolveAll(); len(docs) != 1 {
+		t.Fatalf("wrote %d documents after Reset, want 1", len(docs))
+	}
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range []string{"Get", OutcomeDimension, "Requests", "Latency"} {
+		if bytes.Contains(buf.Bytes(), []byte(`"`+member+`"`)) {
+			t.Errorf("document after Reset holds %q: %s", member, buf.Bytes())
+		}
+	}
+
+	m.RecordOutcome("Get", nil)
+	if _, ok := metricDimension(&m, "Get", OutcomeDimension); !ok {
+		t.Error("outcome recorded after Reset is missing")
+	}
+}
+
+func TestEndWindowClearsWindowedScopedMetrics(t *testing.T) {
+	m := NewMetric("NS")
+	m.SetWindowed("Get")
+	m.RecordOutcome("Get", nil)
+	m.RecordOutcome("Put", nil)
+	m.AddMetricForDimensions(map[string]string{"Operation": "Get"}, "Get", Count, 1)
+
+	snap := m.EndWindow()
+	for _, op := range []string{"Get", "Put"} {
+		if _, ok := metricDimension(&snap, op, OutcomeDimension); !ok {
+			t.Errorf("snapshot lacks %s", op)
+		}
+	}
+	if _, ok := metricDimension(&m, "Get", OutcomeDimension); ok {
+		t.Error("windowed scoped metric Get survived EndWindow")
+	}
+	if _, ok := metricDimension(&m, "Put", OutcomeDimension); !ok {
+		t.Error("scoped metric Put was cleared by EndWindow")
+	}
+	if docs := m.ResolveAll(); len(docs) != 1 {
+		t.Errorf("wrote %d documents after EndWindow, want the one of Put", len(docs))
+	}
+}